@@ -0,0 +1,189 @@
+package httpstat
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// altSvc is an alternative service advertised with the Alt-Svc header.
+type altSvc struct {
+	proto  string
+	addr   string
+	maxAge time.Duration
+}
+
+// parseAltSvc parses an Alt-Svc header value as defined by RFC 7838,
+// returning nil for "clear". Alternatives without a host use host.
+func parseAltSvc(v, host string) []altSvc {
+	var services []altSvc
+
+	for _, field := range strings.Split(v, ",") {
+		params := strings.Split(field, ";")
+
+		proto, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok {
+			continue
+		}
+
+		authority = strings.Trim(authority, `"`)
+		h, port, err := net.SplitHostPort(authority)
+		if err != nil {
+			continue
+		}
+
+		if h == "" {
+			h = host
+		}
+
+		s := altSvc{
+			proto:  proto,
+			addr:   net.JoinHostPort(h, port),
+			maxAge: 24 * time.Hour,
+		}
+
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name != "ma" {
+				continue
+			}
+
+			if n, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				s.maxAge = time.Duration(n) * time.Second
+			}
+		}
+
+		services = append(services, s)
+	}
+
+	return services
+}
+
+// altSvcCache remembers the HTTP/3 alternatives of origins.
+type altSvcCache struct {
+	mu      sync.Mutex
+	entries map[string]altSvcEntry
+}
+
+// altSvcEntry is a cached alternative.
+type altSvcEntry struct {
+	addr    string
+	expires time.Time
+}
+
+// lookup returns the HTTP/3 alternative address of origin.
+func (c *altSvcCache) lookup(origin string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[origin]
+	if !ok {
+		return "", false
+	}
+
+	if time.Now().After(e.expires) {
+		delete(c.entries, origin)
+		return "", false
+	}
+
+	return e.addr, true
+}
+
+// update the alternative of origin from the Alt-Svc header values.
+func (c *altSvcCache) update(origin string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	host, _, _ := net.SplitHostPort(origin)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]altSvcEntry)
+	}
+
+	for _, v := range values {
+		if strings.TrimSpace(v) == "clear" {
+			delete(c.entries, origin)
+			return
+		}
+
+		for _, s := range parseAltSvc(v, host) {
+			if s.proto != "h3" {
+				continue
+			}
+
+			c.entries[origin] = altSvcEntry{
+				addr:    s.addr,
+				expires: time.Now().Add(s.maxAge),
+			}
+			return
+		}
+	}
+}
+
+// remove the alternative of origin.
+func (c *altSvcCache) remove(origin string) {
+	c.mu.Lock()
+	delete(c.entries, origin)
+	c.mu.Unlock()
+}
+
+// altSvcTransport upgrades requests to HTTP/3 for origins
+// which advertised it with the Alt-Svc response header.
+type altSvcTransport struct {
+	tcp   http.RoundTripper
+	h3    http.RoundTripper
+	cache *altSvcCache
+}
+
+// RoundTrip implementation.
+func (t *altSvcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.tcp.RoundTrip(req)
+	}
+
+	o := origin(req.URL)
+
+	if _, ok := t.cache.lookup(o); ok {
+		res, err := t.h3.RoundTrip(req)
+		if err == nil {
+			return res, nil
+		}
+
+		// the body is consumed by failed attempts
+		if req.Body != nil && req.Body != http.NoBody {
+			return nil, err
+		}
+
+		t.cache.remove(o)
+	}
+
+	res, err := t.tcp.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.cache.update(o, res.Header.Values("Alt-Svc"))
+
+	return res, nil
+}
+
+// origin returns the host and port of u.
+func origin(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,219 @@
+package httpstat
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"slices"
+	"time"
+)
+
+// Cipher suite orders.
+const (
+	CipherOrderServer = "server"
+	CipherOrderClient = "client"
+)
+
+// auditVersions are the protocol versions audited.
+var auditVersions = []uint16{
+	tls.VersionTLS10,
+	tls.VersionTLS11,
+	tls.VersionTLS12,
+	tls.VersionTLS13,
+}
+
+// TLSAudit is the TLS configuration accepted by a server.
+type TLSAudit struct {
+	// Addr audited.
+	Addr string `json:"addr"`
+
+	// Versions of the protocol, supported or not.
+	Versions []TLSVersion `json:"versions"`
+
+	// CipherSuites accepted with TLS 1.2 and earlier, whose suites are
+	// configurable. The TLS 1.3 suite negotiated is that of its version.
+	CipherSuites []TLSCipherSuite `json:"cipher_suites,omitempty"`
+
+	// CipherOrder is CipherOrderServer when the server chooses among the
+	// suites offered by its own preference, CipherOrderClient when it
+	// chooses by the client's, or empty when fewer than two are accepted.
+	CipherOrder string `json:"cipher_order,omitempty"`
+}
+
+// TLSVersion is the result of a handshake constrained to a protocol version.
+type TLSVersion struct {
+	// Version of the protocol, such as "TLS 1.2".
+	Version string `json:"version"`
+
+	// Supported is true when the handshake completed.
+	Supported bool `json:"supported"`
+
+	// CipherSuite negotiated.
+	CipherSuite string `json:"cipher_suite,omitempty"`
+
+	// Time of the handshake.
+	Time time.Duration `json:"time"`
+
+	// Error of the handshake.
+	Error string `json:"error,omitempty"`
+}
+
+// TLSCipherSuite is a cipher suite accepted by a server.
+type TLSCipherSuite struct {
+	// Name of the suite, such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+	Name string `json:"name"`
+
+	// Version of the protocol negotiated with the suite.
+	Version string `json:"version"`
+
+	// Insecure is true when the suite has known security issues.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// Time of the handshake.
+	Time time.Duration `json:"time"`
+}
+
+// AuditTLS performs a series of handshakes with the server at addr,
+// defaulting to port 443, each constrained to a protocol version or
+// cipher suite. Certificates are not verified, so that servers with
+// invalid certificates may be audited. The error is only set when
+// the server cannot be connected to.
+func AuditTLS(addr string, options ...Option) (*TLSAudit, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "443")
+	}
+
+	a := &auditor{
+		dialer: newDialer(newConfig(options)),
+		addr:   addr,
+	}
+
+	v := &TLSAudit{
+		Addr: addr,
+	}
+
+	for _, version := range auditVersions {
+		r := TLSVersion{
+			Version: tls.VersionName(version),
+		}
+
+		cs, d, err := a.handshake(&tls.Config{
+			MinVersion: version,
+			MaxVersion: version,
+		})
+
+		r.Time = d
+
+		var dialErr *net.OpError
+		switch {
+		case errors.As(err, &dialErr) && dialErr.Op == "dial":
+			return nil, opError(dialErr)
+		case err != nil:
+			r.Error = err.Error()
+		default:
+			r.Supported = true
+			r.CipherSuite = tls.CipherSuiteName(cs.CipherSuite)
+		}
+
+		v.Versions = append(v.Versions, r)
+	}
+
+	var accepted []uint16
+
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if !slices.ContainsFunc(suite.SupportedVersions, func(v uint16) bool { return v <= tls.VersionTLS12 }) {
+			continue
+		}
+
+		cs, d, err := a.handshake(&tls.Config{
+			MinVersion:   tls.VersionTLS10,
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{suite.ID},
+		})
+
+		if err != nil {
+			continue
+		}
+
+		accepted = append(accepted, suite.ID)
+
+		v.CipherSuites = append(v.CipherSuites, TLSCipherSuite{
+			Name:     suite.Name,
+			Version:  tls.VersionName(cs.Version),
+			Insecure: suite.Insecure,
+			Time:     d,
+		})
+	}
+
+	v.CipherOrder = a.order(accepted)
+
+	return v, nil
+}
+
+// auditor performs the handshakes of an audit.
+type auditor struct {
+	dialer *dialer
+	addr   string
+}
+
+// order returns the cipher suite order of the server, offering the
+// first two accepted suites in both orders and comparing the suites
+// chosen. It is empty when undetermined.
+func (a *auditor) order(suites []uint16) string {
+	if len(suites) < 2 {
+		return ""
+	}
+
+	var chosen [2]uint16
+	for i, offered := range [][]uint16{{suites[0], suites[1]}, {suites[1], suites[0]}} {
+		cs, _, err := a.handshake(&tls.Config{
+			MinVersion:   tls.VersionTLS10,
+			MaxVersion:   tls.VersionTLS12,
+			CipherSuites: offered,
+		})
+
+		if err != nil {
+			return ""
+		}
+
+		chosen[i] = cs.CipherSuite
+	}
+
+	if chosen[0] == chosen[1] {
+		return CipherOrderServer
+	}
+
+	return CipherOrderClient
+}
+
+// handshake with the server using config, returning
+// the connection state and time of the handshake.
+func (a *auditor) handshake(config *tls.Config) (tls.ConnectionState, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.dialer.Timeout+a.dialer.TLSHandshakeTimeout)
+	defer cancel()
+
+	c, err := a.dialer.dial(ctx, "tcp", a.addr)
+	if err != nil {
+		return tls.ConnectionState{}, 0, err
+	}
+	defer c.Close()
+
+	base := a.dialer.TLSConfig
+	config.ServerName = base.ServerName
+	config.Certificates = base.Certificates
+	config.GetClientCertificate = base.GetClientCertificate
+	config.InsecureSkipVerify = true
+
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(a.addr)
+	}
+
+	tc := tls.Client(c, config)
+
+	start := time.Now()
+	err = tc.HandshakeContext(ctx)
+	d := time.Since(start)
+
+	return tc.ConnectionState(), d, err
+}
@@ -0,0 +1,81 @@
+package httpstat_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// supported returns the supported versions of v.
+func supported(v *httpstat.TLSAudit) (versions []string) {
+	for _, r := range v.Versions {
+		if r.Supported {
+			versions = append(versions, r.Version)
+		}
+	}
+	return
+}
+
+func TestAuditTLS(t *testing.T) {
+	t.Run("with defaults", func(t *testing.T) {
+		s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+		defer s.Close()
+
+		v, err := httpstat.AuditTLS(s.Listener.Addr().String())
+		assert.NoError(t, err, "audit")
+		assert.Equal(t, s.Listener.Addr().String(), v.Addr)
+		assert.Len(t, v.Versions, 4)
+		assert.Equal(t, []string{"TLS 1.2", "TLS 1.3"}, supported(v))
+		assert.Equal(t, "TLS_AES_128_GCM_SHA256", v.Versions[3].CipherSuite)
+		assert.NotZero(t, v.Versions[3].Time, "time")
+		assert.NotEmpty(t, v.Versions[0].Error, "error")
+		assert.NotEmpty(t, v.CipherSuites, "cipher suites")
+		assert.Equal(t, httpstat.CipherOrderServer, v.CipherOrder)
+	})
+
+	t.Run("with legacy versions and ciphers", func(t *testing.T) {
+		s := httptest.NewUnstartedServer(http.HandlerFunc(noRedirects))
+		s.TLS = &tls.Config{
+			MinVersion: tls.VersionTLS10,
+			MaxVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			},
+		}
+		s.StartTLS()
+		defer s.Close()
+
+		v, err := httpstat.AuditTLS(s.Listener.Addr().String())
+		assert.NoError(t, err, "audit")
+		assert.Equal(t, []string{"TLS 1.0", "TLS 1.1", "TLS 1.2"}, supported(v))
+
+		insecure := make(map[string]bool)
+		for _, cs := range v.CipherSuites {
+			insecure[cs.Name] = cs.Insecure
+			assert.Equal(t, "TLS 1.2", cs.Version)
+			assert.NotZero(t, cs.Time, "time")
+		}
+
+		assert.Equal(t, map[string]bool{
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256": false,
+			"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":    false,
+			"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256": true,
+		}, insecure)
+		assert.Equal(t, httpstat.CipherOrderServer, v.CipherOrder)
+	})
+
+	t.Run("with an unreachable server", func(t *testing.T) {
+		s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+		s.Close()
+
+		_, err := httpstat.AuditTLS(s.Listener.Addr().String())
+		assert.EqualError(t, err, "connection refused")
+	})
+}
@@ -0,0 +1,23 @@
+package httpstat
+
+import (
+	"syscall"
+)
+
+// bindToDevice returns a socket control function binding
+// sockets to the network interface name.
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+
+		cerr := c.Control(func(fd uintptr) {
+			err = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+		})
+
+		if cerr != nil {
+			return cerr
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,25 @@
+package httpstat_test
+
+import (
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+func TestResponse_Interface(t *testing.T) {
+	s := server(noRedirects)
+	defer s.Close()
+
+	t.Run("with an interface", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithInterface("lo"))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+	})
+
+	t.Run("with an unknown interface", func(t *testing.T) {
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithInterface("nope0"))
+		assert.Error(t, err, "request")
+	})
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package httpstat
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToDevice returns a socket control function binding
+// sockets to the network interface name, which is only
+// supported on Linux.
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return errors.New("binding to an interface is only supported on Linux")
+	}
+}
@@ -0,0 +1,250 @@
+package httpstat
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Check is a request whose response is asserted, such as one loaded
+// from a check file by LoadChecks.
+type Check struct {
+	// Name of the check, defaulting to its URL.
+	Name string
+
+	// Method of the request, defaulting to GET.
+	Method string
+
+	// URL of the request.
+	URL string
+
+	// Header of the request.
+	Header http.Header
+
+	// Body of the request.
+	Body string
+
+	// Timeout of the request, defaulting to that of WithTimeout.
+	Timeout time.Duration
+
+	// Interval between runs when scheduled.
+	Interval time.Duration
+
+	// TLS options.
+	TLS CheckTLS
+
+	// Redirects followed.
+	Redirects RedirectPolicy
+
+	// Assert on the response.
+	Assert Assertions
+
+	// Line of the check in its file, if any.
+	Line int
+}
+
+// CheckTLS is the TLS options of a check.
+type CheckTLS struct {
+	// Insecure disables the verification of server certificates.
+	Insecure bool
+
+	// ServerName sent and verified in place of the host.
+	ServerName string
+
+	// RootCA is a PEM file of the root certificate authorities.
+	RootCA string
+
+	// ClientCert and ClientKey are PEM files of the client certificate.
+	ClientCert string
+	ClientKey  string
+}
+
+// Assertions on the response of a check.
+type Assertions struct {
+	// Status is the statuses expected, or any status below 400 when empty.
+	Status []int
+
+	// MaxTime is the max total time of the request, including redirects.
+	MaxTime time.Duration
+
+	// Header values expected.
+	Header map[string]string
+
+	// BodyContains is a string the body is expected to contain.
+	BodyContains string
+}
+
+// AssertionError is the error of a check whose response failed assertions.
+type AssertionError struct {
+	// Failures of the assertions.
+	Failures []string
+}
+
+// Error implementation.
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + strings.Join(e.Failures, ", ")
+}
+
+// Run performs the request of the check, returning an AssertionError
+// along with the response when the assertions fail. The options are
+// applied before those of the check, which take precedence.
+func (c *Check) Run(ctx context.Context, options ...Option) (Response, error) {
+	o, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+
+	method := c.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	var body io.Reader
+	if c.Body != "" {
+		body = strings.NewReader(c.Body)
+	}
+
+	var b bytes.Buffer
+	client := NewClient(append(options[:len(options):len(options)], o...)...)
+
+	res, err := request(ctx, client, method, c.URL, c.Header, body, &limitWriter{&b, maxStepBody})
+	if err != nil {
+		return nil, err
+	}
+
+	if failures := c.Assert.check(res, b.Bytes()); len(failures) > 0 {
+		return res, &AssertionError{Failures: failures}
+	}
+
+	return res, nil
+}
+
+// options returns the options of the check.
+func (c *Check) options() ([]Option, error) {
+	var o []Option
+
+	if c.Redirects != (RedirectPolicy{}) {
+		o = append(o, WithRedirectPolicy(c.Redirects))
+	}
+
+	if c.TLS.Insecure {
+		o = append(o, WithInsecure(true))
+	}
+
+	if c.Timeout > 0 {
+		o = append(o, WithTimeout(c.Timeout))
+	}
+
+	if c.TLS.ServerName != "" {
+		o = append(o, WithServerName(c.TLS.ServerName))
+	}
+
+	if c.TLS.RootCA != "" {
+		pool, err := loadRootCA(c.TLS.RootCA)
+		if err != nil {
+			return nil, err
+		}
+		o = append(o, WithRootCAs(pool))
+	}
+
+	if c.TLS.ClientCert != "" {
+		o = append(o, WithClientCertificateFile(c.TLS.ClientCert, c.TLS.ClientKey))
+	}
+
+	return o, nil
+}
+
+// check returns the failures of the assertions on res and its body.
+func (a Assertions) check(res Response, body []byte) (failures []string) {
+	switch {
+	case len(a.Status) > 0 && !slices.Contains(a.Status, res.Status()):
+		failures = append(failures, fmt.Sprintf("status is %d, expected %s", res.Status(), statusList(a.Status)))
+	case len(a.Status) == 0 && res.Status() >= 400:
+		failures = append(failures, fmt.Sprintf("status is %d", res.Status()))
+	}
+
+	if a.MaxTime > 0 {
+		if d := res.TimeTotalWithRedirects(time.Now()); d > a.MaxTime {
+			failures = append(failures, fmt.Sprintf("total time is %s, expected at most %s", d.Round(time.Millisecond), a.MaxTime))
+		}
+	}
+
+	for _, name := range sortedKeys(a.Header) {
+		if v := res.Header().Get(name); v != a.Header[name] {
+			failures = append(failures, fmt.Sprintf("header %s is %q, expected %q", name, v, a.Header[name]))
+		}
+	}
+
+	if a.BodyContains != "" && !bytes.Contains(body, []byte(a.BodyContains)) {
+		failures = append(failures, fmt.Sprintf("body does not contain %q", a.BodyContains))
+	}
+
+	return
+}
+
+// statusList returns the statuses separated by "or".
+func statusList(statuses []int) string {
+	var s []string
+	for _, code := range statuses {
+		s = append(s, fmt.Sprint(code))
+	}
+
+	return strings.Join(s, " or ")
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+// loadRootCA loads the PEM file of root certificate authorities at path.
+func loadRootCA(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.New("no certificates found in " + path)
+	}
+
+	return pool, nil
+}
+
+// validURL returns an error unless uri is a valid URL of a check.
+func validURL(uri string) error {
+	if _, _, ok := unixURL(uri); ok {
+		return nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return errors.Unwrap(err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http, https or unix")
+	}
+
+	if u.Host == "" {
+		return errors.New("host is missing")
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package httpstat_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+func TestLoadChecks(t *testing.T) {
+	t.Run("with YAML", func(t *testing.T) {
+		checks, err := httpstat.LoadChecks(strings.NewReader(`
+checks:
+  - name: login
+    method: POST
+    url: https://example.com/login
+    headers:
+      Content-Type: application/json
+      Accept: [text/html, application/json]
+    body: '{"user": "tobi"}'
+    timeout: 5s
+    interval: 1m
+    tls:
+      server_name: example.org
+      insecure: true
+    redirects:
+      max: 3
+      same_origin: true
+      follow: false
+    assert:
+      status: [200, 204]
+      max_time: 500ms
+      headers:
+        Content-Type: application/json
+      body_contains: welcome
+  - url: unix:///run/app.sock:/healthz
+`))
+
+		assert.NoError(t, err, "load")
+		assert.Len(t, checks, 2)
+
+		c := checks[0]
+		assert.Equal(t, "login", c.Name)
+		assert.Equal(t, "POST", c.Method)
+		assert.Equal(t, "https://example.com/login", c.URL)
+		assert.Equal(t, []string{"text/html", "application/json"}, c.Header.Values("Accept"))
+		assert.Equal(t, `{"user": "tobi"}`, c.Body)
+		assert.Equal(t, 5*time.Second, c.Timeout)
+		assert.Equal(t, time.Minute, c.Interval)
+		assert.Equal(t, httpstat.CheckTLS{ServerName: "example.org", Insecure: true}, c.TLS)
+		assert.Equal(t, httpstat.RedirectPolicy{MaxRedirects: 3, SameOrigin: true, NoFollow: true}, c.Redirects)
+		assert.Equal(t, []int{200, 204}, c.Assert.Status)
+		assert.Equal(t, 500*time.Millisecond, c.Assert.MaxTime)
+		assert.Equal(t, map[string]string{"Content-Type": "application/json"}, c.Assert.Header)
+		assert.Equal(t, "welcome", c.Assert.BodyContains)
+		assert.Equal(t, 3, c.Line)
+
+		assert.Equal(t, "unix:///run/app.sock:/healthz", checks[1].Name)
+		assert.Equal(t, 25, checks[1].Line)
+	})
+
+	t.Run("with JSON", func(t *testing.T) {
+		checks, err := httpstat.LoadChecks(strings.NewReader(`{
+  "checks": [
+    {"url": "http://example.com", "assert": {"status": 301}}
+  ]
+}`))
+
+		assert.NoError(t, err, "load")
+		assert.Len(t, checks, 1)
+		assert.Equal(t, []int{301}, checks[0].Assert.Status)
+		assert.Equal(t, 3, checks[0].Line)
+	})
+
+	t.Run("with anchors", func(t *testing.T) {
+		checks, err := httpstat.LoadChecks(strings.NewReader(`
+x-defaults: &defaults
+  timeout: 5s
+  headers:
+    Accept: application/json
+  assert: &assert
+    status: 200
+
+x-tls: &tls
+  tls:
+    insecure: true
+    server_name: example.org
+
+checks:
+  - <<: *defaults
+    url: https://example.com/a
+    timeout: 1s
+  - <<: [*tls, *defaults]
+    url: https://example.com/b
+    assert: *assert
+`))
+
+		assert.NoError(t, err, "load")
+		assert.Len(t, checks, 2)
+
+		a := checks[0]
+		assert.Equal(t, time.Second, a.Timeout)
+		assert.Equal(t, "application/json", a.Header.Get("Accept"))
+		assert.Equal(t, []int{200}, a.Assert.Status)
+		assert.Equal(t, httpstat.CheckTLS{}, a.TLS)
+
+		b := checks[1]
+		assert.Equal(t, 5*time.Second, b.Timeout)
+		assert.Equal(t, httpstat.CheckTLS{ServerName: "example.org", Insecure: true}, b.TLS)
+		assert.Equal(t, []int{200}, b.Assert.Status)
+	})
+
+	t.Run("with an invalid merge", func(t *testing.T) {
+		_, err := httpstat.LoadChecks(strings.NewReader(`
+x-timeout: &timeout 5s
+
+checks:
+  - <<: *timeout
+    url: https://example.com
+`))
+
+		assert.EqualError(t, err, `line 5: checks[0].<<: must be a mapping`)
+	})
+
+	t.Run("with invalid fields", func(t *testing.T) {
+		_, err := httpstat.LoadChecks(strings.NewReader(`checks:
+  - name: home
+    url: ftp://example.com
+    timeout: 5 seconds
+    assert:
+      status: [200, "ok", 700, 0x1F4]
+  - name: home
+    method: "GET /"
+    url: https://example.com
+    redirect:
+      max: 3
+    tls:
+      client_cert: cert.pem
+  - tls:
+      insecure: yes please
+      root_ca: missing/ca.pem
+      client_cert: missing/cert.pem
+      client_key: missing/key.pem
+`))
+
+		assert.Error(t, err)
+		assert.Equal(t, strings.Join([]string{
+			`line 3: checks[0].url: invalid URL "ftp://example.com": scheme must be http, https or unix`,
+			`line 4: checks[0].timeout: must be a positive duration such as "5s"`,
+			`line 6: checks[0].assert.status[1]: must be an integer`,
+			`line 6: checks[0].assert.status[2]: invalid status 700`,
+			`line 6: checks[0].assert.status[3]: must be an integer`,
+			`line 7: checks[1].name: duplicate name "home"`,
+			`line 8: checks[1].method: invalid method "GET /"`,
+			`line 10: checks[1].redirect: unknown field`,
+			`line 13: checks[1].tls: client_cert and client_key must be set together`,
+			`line 14: checks[2].url: is required`,
+			`line 15: checks[2].tls.insecure: must be true or false`,
+			`line 16: checks[2].tls.root_ca: open missing/ca.pem: no such file or directory`,
+			`line 17: checks[2].tls.client_cert: open missing/cert.pem: no such file or directory`,
+		}, "\n"), err.Error())
+
+		var e *httpstat.FieldError
+		assert.True(t, errors.As(err, &e), "field error")
+		assert.Equal(t, 3, e.Line)
+		assert.Equal(t, "checks[0].url", e.Field)
+	})
+}
+
+func TestCheck_Run(t *testing.T) {
+	s := server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello " + r.Method))
+	})
+	defer s.Close()
+
+	t.Run("with passing assertions", func(t *testing.T) {
+		c := &httpstat.Check{
+			Method: "POST",
+			URL:    s.URL,
+			Assert: httpstat.Assertions{
+				Status:       []int{200},
+				MaxTime:      time.Second,
+				Header:       map[string]string{"Content-Type": "text/plain"},
+				BodyContains: "hello POST",
+			},
+		}
+
+		res, err := c.Run(context.Background())
+		assert.NoError(t, err, "run")
+		assert.Equal(t, 200, res.Status())
+	})
+
+	t.Run("with failing assertions", func(t *testing.T) {
+		c := &httpstat.Check{
+			URL: s.URL,
+			Assert: httpstat.Assertions{
+				Status:       []int{201, 204},
+				Header:       map[string]string{"Content-Type": "application/json"},
+				BodyContains: "hello POST",
+			},
+		}
+
+		res, err := c.Run(context.Background())
+		assert.EqualError(t, err, `assertion failed: status is 200, expected 201 or 204, header Content-Type is "text/plain", expected "application/json", body does not contain "hello POST"`)
+		assert.Equal(t, 200, res.Status())
+
+		var e *httpstat.AssertionError
+		assert.True(t, errors.As(err, &e), "assertion error")
+		assert.Len(t, e.Failures, 3)
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		s := server(noRedirects)
+		defer s.Close()
+
+		c := &httpstat.Check{URL: s.URL, Timeout: 10 * time.Millisecond}
+		_, err := c.Run(context.Background(), httpstat.WithTimeout(5*time.Second))
+		assert.EqualError(t, err, "timeout exceeded")
+
+		c = &httpstat.Check{URL: s.URL}
+		_, err = c.Run(context.Background(), httpstat.WithTimeout(10*time.Millisecond))
+		assert.EqualError(t, err, "timeout exceeded")
+	})
+
+	t.Run("with a cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := (&httpstat.Check{URL: s.URL}).Run(ctx)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,458 @@
+package httpstat
+
+import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is the error of an invalid field of a check file.
+type FieldError struct {
+	// Line of the field.
+	Line int
+
+	// Field path, such as "checks[2].timeout".
+	Field string
+
+	// Err of the field.
+	Err error
+}
+
+// Error implementation.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Err)
+}
+
+// Unwrap implementation.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadChecks loads the checks of a YAML or JSON check file such as:
+//
+//	checks:
+//	  - name: login
+//	    method: POST
+//	    url: https://example.com/login
+//	    headers:
+//	      Content-Type: application/json
+//	    body: '{"user": "tobi"}'
+//	    timeout: 5s
+//	    interval: 1m
+//	    tls:
+//	      server_name: example.com
+//	      root_ca: ca.pem
+//	      client_cert: cert.pem
+//	      client_key: key.pem
+//	      insecure: false
+//	    redirects:
+//	      max: 3
+//	      same_origin: true
+//	      no_downgrade: true
+//	      follow: true
+//	    assert:
+//	      status: [200, 204]
+//	      max_time: 500ms
+//	      headers:
+//	        Content-Type: application/json
+//	      body_contains: welcome
+//
+// Anchors, aliases and merge keys such as "<<: *defaults" may be used to
+// share settings between checks, with top-level fields prefixed with "x-",
+// such as "x-defaults", being ignored so that they may hold anchors.
+//
+// Only url is required. The files of root_ca, client_cert and client_key
+// are loaded to validate them, with relative paths being relative to the
+// working directory rather than the check file. Invalid fields are reported
+// together, each as a FieldError with its line, joined by errors.Join in
+// the order of their lines.
+func LoadChecks(r io.Reader) ([]*Check, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	d := &checkDecoder{}
+	var checks []*Check
+
+	d.fields(doc.Content[0], "", func(key string, n *yaml.Node, field string) bool {
+		if strings.HasPrefix(key, "x-") {
+			return true
+		}
+
+		if key != "checks" {
+			return false
+		}
+
+		names := make(map[string]bool)
+		d.list(n, field, func(n *yaml.Node, field string) {
+			c := d.check(n, field)
+			if names[c.Name] {
+				d.fail(n, field+".name", "duplicate name %q", c.Name)
+			}
+			names[c.Name] = true
+			checks = append(checks, c)
+		})
+
+		return true
+	})
+
+	if len(d.errs) > 0 {
+		slices.SortStableFunc(d.errs, func(a, b error) int {
+			return cmp.Compare(a.(*FieldError).Line, b.(*FieldError).Line)
+		})
+		return nil, errors.Join(d.errs...)
+	}
+
+	return checks, nil
+}
+
+// checkDecoder decodes the nodes of a check file, collecting errors.
+type checkDecoder struct {
+	errs []error
+}
+
+// fail records an error of the field of n.
+func (d *checkDecoder) fail(n *yaml.Node, field, format string, args ...any) {
+	d.errs = append(d.errs, &FieldError{
+		Line:  n.Line,
+		Field: field,
+		Err:   fmt.Errorf(format, args...),
+	})
+}
+
+// check decodes a check.
+func (d *checkDecoder) check(n *yaml.Node, field string) *Check {
+	c := &Check{
+		Line: n.Line,
+	}
+
+	d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+		switch key {
+		case "name":
+			c.Name = d.string(n, field)
+		case "method":
+			c.Method = d.string(n, field)
+			if !validMethod(c.Method) {
+				d.fail(n, field, "invalid method %q", c.Method)
+			}
+		case "url":
+			c.URL = d.string(n, field)
+			if err := validURL(c.URL); err != nil {
+				d.fail(n, field, "invalid URL %q: %s", c.URL, err)
+			}
+		case "headers":
+			c.Header = d.header(n, field)
+		case "body":
+			c.Body = d.string(n, field)
+		case "timeout":
+			c.Timeout = d.duration(n, field)
+		case "interval":
+			c.Interval = d.duration(n, field)
+		case "tls":
+			c.TLS = d.tls(n, field)
+		case "redirects":
+			c.Redirects = d.redirects(n, field)
+		case "assert":
+			c.Assert = d.assertions(n, field)
+		default:
+			return false
+		}
+		return true
+	})
+
+	if c.URL == "" {
+		d.fail(n, field+".url", "is required")
+	}
+
+	if c.Name == "" {
+		c.Name = c.URL
+	}
+
+	return c
+}
+
+// tls decodes the TLS options of a check.
+func (d *checkDecoder) tls(n *yaml.Node, field string) (v CheckTLS) {
+	var cert *yaml.Node
+
+	d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+		switch key {
+		case "insecure":
+			v.Insecure = d.bool(n, field)
+		case "server_name":
+			v.ServerName = d.string(n, field)
+		case "root_ca":
+			v.RootCA = d.string(n, field)
+			if _, err := loadRootCA(v.RootCA); err != nil {
+				d.fail(n, field, "%s", err)
+			}
+		case "client_cert":
+			v.ClientCert = d.string(n, field)
+			cert = n
+		case "client_key":
+			v.ClientKey = d.string(n, field)
+		default:
+			return false
+		}
+		return true
+	})
+
+	switch {
+	case (v.ClientCert == "") != (v.ClientKey == ""):
+		d.fail(n, field, "client_cert and client_key must be set together")
+	case v.ClientCert != "":
+		if _, err := tls.LoadX509KeyPair(v.ClientCert, v.ClientKey); err != nil {
+			d.fail(cert, field+".client_cert", "%s", err)
+		}
+	}
+
+	return
+}
+
+// redirects decodes the redirect policy of a check.
+func (d *checkDecoder) redirects(n *yaml.Node, field string) (v RedirectPolicy) {
+	d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+		switch key {
+		case "max":
+			v.MaxRedirects, _ = d.int(n, field)
+			if v.MaxRedirects < 0 {
+				d.fail(n, field, "must not be negative")
+			}
+		case "same_origin":
+			v.SameOrigin = d.bool(n, field)
+		case "no_downgrade":
+			v.NoDowngrade = d.bool(n, field)
+		case "follow":
+			v.NoFollow = !d.bool(n, field)
+		default:
+			return false
+		}
+		return true
+	})
+
+	return
+}
+
+// assertions decodes the assertions of a check.
+func (d *checkDecoder) assertions(n *yaml.Node, field string) (v Assertions) {
+	d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+		switch key {
+		case "status":
+			v.Status = d.statuses(n, field)
+		case "max_time":
+			v.MaxTime = d.duration(n, field)
+		case "headers":
+			v.Header = make(map[string]string)
+			d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+				v.Header[key] = d.string(n, field)
+				return true
+			})
+		case "body_contains":
+			v.BodyContains = d.string(n, field)
+		default:
+			return false
+		}
+		return true
+	})
+
+	return
+}
+
+// statuses decodes a status or list of statuses.
+func (d *checkDecoder) statuses(n *yaml.Node, field string) (v []int) {
+	status := func(n *yaml.Node, field string) {
+		code, ok := d.int(n, field)
+		if ok && (code < 100 || code > 599) {
+			d.fail(n, field, "invalid status %d", code)
+		}
+		v = append(v, code)
+	}
+
+	if n.Kind == yaml.SequenceNode {
+		d.list(n, field, status)
+	} else {
+		status(n, field)
+	}
+
+	return
+}
+
+// header decodes a mapping of header names to a value or list of values.
+func (d *checkDecoder) header(n *yaml.Node, field string) http.Header {
+	h := make(http.Header)
+
+	d.fields(n, field, func(key string, n *yaml.Node, field string) bool {
+		if n.Kind == yaml.SequenceNode {
+			d.list(n, field, func(n *yaml.Node, field string) {
+				h.Add(key, d.string(n, field))
+			})
+		} else {
+			h.Add(key, d.string(n, field))
+		}
+		return true
+	})
+
+	return h
+}
+
+// fields calls fn with the key, value and path of each field of the
+// mapping n, failing when n is not a mapping or fn returns false.
+func (d *checkDecoder) fields(n *yaml.Node, field string, fn func(key string, n *yaml.Node, field string) bool) {
+	n = dealias(n)
+	if n.Kind != yaml.MappingNode {
+		d.fail(n, fieldName(field), "must be a mapping")
+		return
+	}
+
+	for _, pair := range d.pairs(n, field) {
+		key, value := pair[0], pair[1]
+		path := key.Value
+		if field != "" {
+			path = field + "." + key.Value
+		}
+
+		if !fn(key.Value, value, path) {
+			d.fail(key, path, "unknown field")
+		}
+	}
+}
+
+// pairs returns the key and value nodes of the fields of the mapping n,
+// including those of its "<<" merge keys which it does not set itself.
+func (d *checkDecoder) pairs(n *yaml.Node, field string) [][2]*yaml.Node {
+	var v, merged [][2]*yaml.Node
+	set := make(map[string]bool)
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], dealias(n.Content[i+1])
+		if key.Tag != "!!merge" {
+			v = append(v, [2]*yaml.Node{key, value})
+			set[key.Value] = true
+			continue
+		}
+
+		// earlier mappings of a sequence take precedence
+		maps := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			maps = value.Content
+		}
+
+		for _, m := range maps {
+			m = dealias(m)
+			if m.Kind != yaml.MappingNode {
+				d.fail(key, fieldName(field)+".<<", "must be a mapping")
+				continue
+			}
+			merged = append(merged, d.pairs(m, field)...)
+		}
+	}
+
+	for _, pair := range merged {
+		if !set[pair[0].Value] {
+			v = append(v, pair)
+			set[pair[0].Value] = true
+		}
+	}
+
+	return v
+}
+
+// dealias returns the node aliased by n, or n.
+func dealias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+
+	return n
+}
+
+// list calls fn with each item of the sequence n and its path.
+func (d *checkDecoder) list(n *yaml.Node, field string, fn func(n *yaml.Node, field string)) {
+	n = dealias(n)
+	if n.Kind != yaml.SequenceNode {
+		d.fail(n, field, "must be a list")
+		return
+	}
+
+	for i, item := range n.Content {
+		fn(dealias(item), fmt.Sprintf("%s[%d]", field, i))
+	}
+}
+
+// string decodes a scalar.
+func (d *checkDecoder) string(n *yaml.Node, field string) string {
+	if n.Kind != yaml.ScalarNode {
+		d.fail(n, field, "must be a string")
+		return ""
+	}
+
+	return n.Value
+}
+
+// int decodes an integer, returning false when n is not one.
+func (d *checkDecoder) int(n *yaml.Node, field string) (int, bool) {
+	v, err := strconv.Atoi(n.Value)
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!int" || err != nil {
+		d.fail(n, field, "must be an integer")
+		return 0, false
+	}
+
+	return v, true
+}
+
+// bool decodes a boolean.
+func (d *checkDecoder) bool(n *yaml.Node, field string) bool {
+	var v bool
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" || n.Decode(&v) != nil {
+		d.fail(n, field, "must be true or false")
+	}
+
+	return v
+}
+
+// duration decodes a positive duration such as "1m30s".
+func (d *checkDecoder) duration(n *yaml.Node, field string) time.Duration {
+	v, err := time.ParseDuration(n.Value)
+	if n.Kind != yaml.ScalarNode || err != nil || v <= 0 {
+		d.fail(n, field, "must be a positive duration such as \"5s\"")
+	}
+
+	return v
+}
+
+// fieldName returns field, or "document" for the root.
+func fieldName(field string) string {
+	if field == "" {
+		return "document"
+	}
+
+	return field
+}
+
+// validMethod returns true if method is a word, such as "GET".
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for _, r := range method {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+
+	return true
+}
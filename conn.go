@@ -0,0 +1,355 @@
+package httpstat
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP/2 client connection preface.
+const preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// HTTP/2 framing.
+const (
+	frameHeaderLen    = 9
+	frameHeaders      = 0x1
+	frameContinuation = 0x9
+	flagEndHeaders    = 0x4
+	flagPadded        = 0x8
+	flagPriority      = 0x20
+)
+
+// Header counter.
+type headerCounter interface {
+	Write([]byte)
+	Start() time.Time
+	Size() int
+	SizeCompressed() int
+}
+
+// conn is a net.Conn which accounts for the header
+// bytes sent and received on the wire.
+type conn struct {
+	net.Conn
+
+	mu   sync.Mutex
+	sent headerCounter
+	recv headerCounter
+	info *TCPInfo
+}
+
+// newConn returns a new conn wrapping c.
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c}
+}
+
+// Write implementation.
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.sent == nil {
+		if bytes.HasPrefix(b, []byte(preface)) {
+			c.sent = newH2Counter(len(preface))
+			c.recv = newH2Counter(0)
+		} else {
+			c.sent = &h1Counter{}
+			c.recv = &h1Counter{}
+		}
+	}
+	c.sent.Write(b)
+	c.mu.Unlock()
+
+	return c.Conn.Write(b)
+}
+
+// Read implementation.
+func (c *conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.mu.Lock()
+	if c.recv != nil {
+		c.recv.Write(b[:n])
+	}
+	c.mu.Unlock()
+
+	return n, err
+}
+
+// Close implementation.
+func (c *conn) Close() error {
+	c.captureTCPInfo()
+	return c.Conn.Close()
+}
+
+// captureTCPInfo captures the kernel TCP information, once.
+func (c *conn) captureTCPInfo() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.info == nil {
+		c.info = tcpInfo(c.Conn)
+	}
+}
+
+// tcpInfo returns the captured kernel TCP information, or nil.
+func (c *conn) tcpInfo() *TCPInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info
+}
+
+// Sizes of the header blocks sent and received.
+func (c *conn) sizes() (sent, sentCompressed, recv, recvCompressed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sent == nil {
+		return
+	}
+
+	return c.sent.Size(), c.sent.SizeCompressed(), c.recv.Size(), c.recv.SizeCompressed()
+}
+
+// proto returns the protocol of the response.
+func (c *conn) proto() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch v := c.recv.(type) {
+	case *h2Counter:
+		return "HTTP/2.0"
+	case *h1Counter:
+		if fields := bytes.Fields(v.first); v.done && len(fields) > 0 {
+			return string(fields[0])
+		}
+	}
+
+	return ""
+}
+
+// firstByte returns the arrival time of the final response header block.
+func (c *conn) firstByte() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.recv == nil {
+		return time.Time{}
+	}
+
+	return c.recv.Start()
+}
+
+// h1Counter counts the bytes of the first final HTTP/1.x header
+// block in a stream, skipping informational (1xx) responses.
+type h1Counter struct {
+	start time.Time
+	n     int
+	lines int
+	first []byte
+	empty bool
+	size  int
+	done  bool
+}
+
+// Write implementation.
+func (c *h1Counter) Write(b []byte) {
+	for _, ch := range b {
+		if c.done {
+			return
+		}
+
+		if c.n == 0 {
+			c.start = time.Now()
+		}
+
+		c.n++
+
+		if c.lines == 0 && ch != '\r' && ch != '\n' && len(c.first) < 16 {
+			c.first = append(c.first, ch)
+		}
+
+		switch ch {
+		case '\r':
+		case '\n':
+			if c.empty && c.lines > 0 {
+				c.end()
+				continue
+			}
+			c.lines++
+			c.empty = true
+		default:
+			c.empty = false
+		}
+	}
+}
+
+// end of a header block.
+func (c *h1Counter) end() {
+	if informational(c.first) {
+		*c = h1Counter{}
+		return
+	}
+
+	c.size = c.n
+	c.done = true
+}
+
+// Start implementation.
+func (c *h1Counter) Start() time.Time {
+	return c.start
+}
+
+// Size implementation.
+func (c *h1Counter) Size() int {
+	return c.size
+}
+
+// SizeCompressed implementation.
+func (c *h1Counter) SizeCompressed() int {
+	return 0
+}
+
+// h2Counter counts the bytes of the first final HTTP/2 header
+// block in a stream, decoding it for the uncompressed size, which
+// is that of the names and values of its fields, pseudo-headers
+// such as ":status" included.
+type h2Counter struct {
+	start          time.Time
+	dec            *hpack.Decoder
+	buf            []byte
+	skip           int
+	n              int
+	compressed     int
+	informational  bool
+	size           int
+	sizeCompressed int
+	done           bool
+}
+
+// newH2Counter returns a new h2Counter skipping the first n bytes.
+func newH2Counter(skip int) *h2Counter {
+	c := &h2Counter{skip: skip}
+	c.dec = hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		c.n += len(f.Name) + len(f.Value)
+		if f.Name == ":status" && strings.HasPrefix(f.Value, "1") {
+			c.informational = true
+		}
+	})
+	return c
+}
+
+// Write implementation.
+func (c *h2Counter) Write(b []byte) {
+	for !c.done {
+		if c.skip > 0 {
+			if len(b) == 0 {
+				return
+			}
+			n := min(c.skip, len(b))
+			c.skip -= n
+			b = b[n:]
+			continue
+		}
+
+		want := frameHeaderLen
+		if len(c.buf) >= frameHeaderLen {
+			length := int(c.buf[0])<<16 | int(c.buf[1])<<8 | int(c.buf[2])
+			if t := c.buf[3]; t != frameHeaders && t != frameContinuation {
+				c.skip = length
+				c.buf = c.buf[:0]
+				continue
+			}
+			want += length
+		}
+
+		if len(c.buf) >= frameHeaderLen && len(c.buf) == want {
+			c.frame(c.buf[3], c.buf[4], c.buf[frameHeaderLen:])
+			c.buf = c.buf[:0]
+			continue
+		}
+
+		if len(b) == 0 {
+			return
+		}
+
+		n := min(want-len(c.buf), len(b))
+		c.buf = append(c.buf, b[:n]...)
+		b = b[n:]
+	}
+}
+
+// frame handles a HEADERS or CONTINUATION frame.
+func (c *h2Counter) frame(typ, flags byte, payload []byte) {
+	if typ == frameHeaders && flags&flagPadded != 0 {
+		if len(payload) == 0 || int(payload[0]) >= len(payload) {
+			c.done = true
+			return
+		}
+		payload = payload[1 : len(payload)-int(payload[0])]
+	}
+
+	if typ == frameHeaders && flags&flagPriority != 0 {
+		if len(payload) < 5 {
+			c.done = true
+			return
+		}
+		payload = payload[5:]
+	}
+
+	if c.compressed == 0 {
+		c.start = time.Now()
+	}
+
+	c.compressed += len(payload)
+
+	if _, err := c.dec.Write(payload); err != nil {
+		c.done = true
+		return
+	}
+
+	if flags&flagEndHeaders == 0 {
+		return
+	}
+
+	if err := c.dec.Close(); err != nil {
+		c.done = true
+		return
+	}
+
+	if c.informational {
+		c.n = 0
+		c.compressed = 0
+		c.informational = false
+		return
+	}
+
+	c.size = c.n
+	c.sizeCompressed = c.compressed
+	c.done = true
+}
+
+// Start implementation.
+func (c *h2Counter) Start() time.Time {
+	return c.start
+}
+
+// Size implementation.
+func (c *h2Counter) Size() int {
+	return c.size
+}
+
+// SizeCompressed implementation.
+func (c *h2Counter) SizeCompressed() int {
+	return c.sizeCompressed
+}
+
+// informational returns true if line is a 1xx status line.
+func informational(line []byte) bool {
+	fields := bytes.Fields(line)
+	return len(fields) > 1 &&
+		bytes.HasPrefix(fields[0], []byte("HTTP/")) &&
+		bytes.HasPrefix(fields[1], []byte("1"))
+}
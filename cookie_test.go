@@ -0,0 +1,63 @@
+package httpstat_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// login redirects requests without a session to /login,
+// which sets the session and redirects back.
+func login(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/login" {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+		http.Redirect(w, r, "/private", http.StatusFound)
+		return
+	}
+
+	if _, err := r.Cookie("session"); err != nil {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	w.Write([]byte("hello world"))
+}
+
+func TestResponse_CookieJar(t *testing.T) {
+	t.Run("with a jar", func(t *testing.T) {
+		s := server(login)
+		defer s.Close()
+
+		jar, err := cookiejar.New(nil)
+		assert.NoError(t, err, "jar")
+
+		res, err := httpstat.Request("GET", s.URL+"/private", nil, nil, httpstat.WithCookieJar(jar))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status())
+		assert.Equal(t, 2, res.Redirects())
+
+		traces := res.Traces()
+		assert.Empty(t, traces[0].Cookies(), "cookies")
+		assert.Len(t, traces[1].Cookies(), 1)
+		assert.Equal(t, "session", traces[1].Cookies()[0].Name)
+		assert.Equal(t, s.URL+"/login", traces[1].URL())
+
+		res, err = httpstat.Request("GET", s.URL+"/private", nil, nil, httpstat.WithCookieJar(jar))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status())
+		assert.Equal(t, 0, res.Redirects())
+	})
+
+	t.Run("without a jar", func(t *testing.T) {
+		s := server(login)
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL+"/private", nil, nil)
+		assert.True(t, errors.Is(err, httpstat.ErrRedirectLoop), "redirect loop")
+	})
+}
@@ -0,0 +1,493 @@
+package httpstat
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// dialer dials connections which account for the header bytes on
+// the wire. TLS connections are handshaken by the dialer, which reports
+// it to the client trace, so that their plaintext stream is observed.
+type dialer struct {
+	net.Dialer
+
+	// Protocol used for requests.
+	Protocol Protocol
+
+	// TLSConfig used for TLS connections.
+	TLSConfig *tls.Config
+
+	// TLSHandshakeTimeout is the max duration of the TLS handshake.
+	TLSHandshakeTimeout time.Duration
+
+	// AltSvc is used to dial the HTTP/3 alternatives of origins.
+	AltSvc *altSvcCache
+
+	// Resolve maps "host:port" addresses to the addresses dialed in their place.
+	Resolve map[string]string
+
+	// DNS resolves hosts in place of the system resolver when set.
+	DNS Resolver
+
+	// Family of the addresses dialed.
+	Family Family
+
+	// SOCKS proxy connections are made through when set.
+	SOCKS *socks
+
+	// Revocation checking of server certificates.
+	Revocation RevocationMode
+}
+
+// override returns the address to dial in place of addr,
+// recording the override applied in the trace of ctx.
+func (d *dialer) override(ctx context.Context, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	to, ok := d.Resolve[net.JoinHostPort(strings.ToLower(host), port)]
+	if !ok {
+		return addr
+	}
+
+	// the port is optional, as with curl's --resolve
+	if h, p, err := net.SplitHostPort(to); err == nil {
+		host, port = h, p
+	} else {
+		host = strings.Trim(to, "[]")
+	}
+
+	to = net.JoinHostPort(host, port)
+
+	if t := traceFromContext(ctx); t != nil {
+		t.override = &Override{
+			From:        addr,
+			To:          to,
+			BypassedDNS: net.ParseIP(host) != nil,
+		}
+	}
+
+	return to
+}
+
+// dial addr, resolving it with the DNS resolver when set. Addresses
+// are tried in order until a connection is established.
+func (d *dialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t := traceFromContext(ctx); t != nil {
+		t.revocationMode = d.Revocation
+	}
+
+	if path, ok := unixSocket(ctx, addr); ok {
+		return d.dialUnix(ctx, path)
+	}
+
+	addr = d.override(ctx, addr)
+	network = d.Family.network(network)
+
+	if d.SOCKS != nil {
+		return d.dialSOCKS(ctx, network, addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if d.DNS == nil || err != nil || net.ParseIP(host) != nil {
+		return d.Dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	for _, ip := range ips {
+		var c net.Conn
+		c, err = d.Dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return c, nil
+		}
+	}
+
+	return nil, err
+}
+
+// dialUnix dials the Unix socket at path, recording it as
+// the address of the trace of ctx. The local address, interface
+// and proxies of the dialer do not apply.
+func (d *dialer) dialUnix(ctx context.Context, path string) (net.Conn, error) {
+	if t := traceFromContext(ctx); t != nil {
+		t.addr = path
+	}
+
+	dialer := net.Dialer{Timeout: d.Timeout}
+	return dialer.DialContext(ctx, "unix", path)
+}
+
+// dialSOCKS dials addr through the SOCKS proxy,
+// recording the negotiation in the trace of ctx.
+func (d *dialer) dialSOCKS(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := d.Dialer.DialContext(ctx, network, d.SOCKS.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := traceFromContext(ctx)
+	if t != nil {
+		t.socksStart = time.Now()
+	}
+
+	err = d.SOCKS.connect(ctx, c, addr)
+
+	if t != nil {
+		t.socksEnd = time.Now()
+	}
+
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// dialTunnel dials addr through a CONNECT tunnel of the proxy of t,
+// recording the proxy as the address of t, and the handshake with an
+// HTTPS proxy and the CONNECT request in its proxy.
+func (d *dialer) dialTunnel(ctx context.Context, network, addr string, t *trace) (net.Conn, error) {
+	p := t.proxy
+	u := p.tunnel
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	t.addr = net.JoinHostPort(u.Hostname(), port)
+
+	c, err := d.dial(ctx, network, t.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	if u.Scheme == "https" {
+		config := d.TLSConfig.Clone()
+		config.ServerName = u.Hostname()
+		config.NextProtos = []string{"http/1.1"}
+
+		hctx, cancel := context.WithTimeout(ctx, d.TLSHandshakeTimeout)
+		defer cancel()
+
+		start := time.Now()
+		tc := tls.Client(c, config)
+		err := tc.HandshakeContext(hctx)
+		p.TimeTLS = time.Since(start)
+
+		// the client certificate is that of the origin
+		t.clientCertRequested = false
+		t.clientCertSent = false
+
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+
+		c = tc
+	}
+
+	start := time.Now()
+	c, err = connect(c, addr, u)
+	p.TimeTunnel = time.Since(start)
+
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		err = ctxErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.Status = http.StatusOK
+	return c, nil
+}
+
+// connect requests a tunnel to addr over c, which is connected to the
+// proxy u, closing c unless established.
+func connect(c net.Conn, addr string, u *url.URL) (net.Conn, error) {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReader(c)
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		c.Close()
+		return nil, fmt.Errorf("proxy responded with %s", res.Status)
+	}
+
+	if r.Buffered() > 0 {
+		return &bufferedConn{c, r}, nil
+	}
+
+	return c, nil
+}
+
+// bufferedConn is a net.Conn whose reads are buffered.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read implementation.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// lookup resolves host, reporting DNS to the client trace of ctx.
+func (d *dialer) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+
+	var addrs []net.IPAddr
+	var err error
+
+	if d.DNS != nil {
+		var v *DNS
+		v, err = d.DNS.Resolve(withoutValues{ctx}, host)
+		if v != nil {
+			for _, ip := range v.Addrs {
+				addrs = append(addrs, net.IPAddr{IP: ip})
+			}
+		}
+		if t := traceFromContext(ctx); t != nil {
+			t.dns = v
+		}
+	} else {
+		resolver := d.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err = resolver.LookupIPAddr(ctx, host)
+	}
+
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	if err == nil {
+		addrs, err = d.filter(host, addrs)
+	}
+
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+	}
+
+	return addrs, err
+}
+
+// filter returns the addresses of the dialer's family.
+func (d *dialer) filter(host string, addrs []net.IPAddr) ([]net.IPAddr, error) {
+	var v []net.IPAddr
+
+	for _, a := range addrs {
+		if d.Family.match(a.IP) {
+			v = append(v, a)
+		}
+	}
+
+	if len(v) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+
+	return v, nil
+}
+
+// DialContext implementation.
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	// HTTP/2 is only used over TLS
+	if d.Protocol == ProtocolHTTP2 {
+		return nil, ErrHTTP2NotSupported
+	}
+
+	c, err := d.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(c), nil
+}
+
+// DialTLSContext implementation.
+func (d *dialer) DialTLSContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	t := traceFromContext(ctx)
+
+	// the transport only speaks HTTP/1.1 to proxies, which
+	// are dialed here for HTTP origins, so never over HTTP/2
+	proxy := t != nil && t.proxy != nil && t.proxy.tunnel == nil
+	requireH2 := d.Protocol == ProtocolHTTP2
+
+	if requireH2 && (proxy || !tlsHTTP2) {
+		return nil, ErrHTTP2NotSupported
+	}
+
+	var c net.Conn
+	var err error
+
+	if t != nil && t.proxy != nil && t.proxy.tunnel != nil {
+		c, err = d.dialTunnel(ctx, network, addr, t)
+	} else {
+		c, err = d.dial(ctx, network, addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{}
+	if d.TLSConfig != nil {
+		config = d.TLSConfig.Clone()
+	}
+
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		config.ServerName = host
+	}
+
+	switch {
+	case proxy:
+		config.NextProtos = []string{"http/1.1"}
+	case config.NextProtos == nil:
+		config.NextProtos = d.Protocol.nextProtos()
+	}
+
+	tc := tls.Client(c, config)
+
+	if err := d.handshake(ctx, tc); err != nil {
+		tc.Close()
+		if requireH2 && noApplicationProtocol(err) {
+			return nil, ErrHTTP2NotSupported
+		}
+		return nil, err
+	}
+
+	if requireH2 && tc.ConnectionState().NegotiatedProtocol != "h2" {
+		tc.Close()
+		return nil, ErrHTTP2NotSupported
+	}
+
+	return &tlsConn{
+		conn: newConn(tc),
+		tls:  tc,
+	}, nil
+}
+
+// handshake performs the TLS handshake of c, reporting it to the client trace of ctx.
+func (d *dialer) handshake(ctx context.Context, c *tls.Conn) error {
+	trace := httptrace.ContextClientTrace(ctx)
+
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+
+	if d.TLSHandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.TLSHandshakeTimeout)
+		defer cancel()
+	}
+
+	err := c.HandshakeContext(ctx)
+
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(c.ConnectionState(), err)
+	}
+
+	return err
+}
+
+// noApplicationProtocol returns true if err is the alert of
+// a server supporting none of the ALPN protocols offered.
+func noApplicationProtocol(err error) bool {
+	var e *net.OpError
+	return errors.As(err, &e) && e.Op == "remote error" && e.Err.Error() == "tls: no application protocol"
+}
+
+// tlsConn is a conn over TLS whose handshake is complete. Its state
+// is read by the transport through ConnectionState, as of Go 1.27.
+type tlsConn struct {
+	*conn
+	tls *tls.Conn
+}
+
+// ConnectionState implementation.
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+// connOf returns the conn wrapped by c, or nil.
+func connOf(c net.Conn) *conn {
+	switch c := c.(type) {
+	case *conn:
+		return c
+	case *tlsConn:
+		return c.conn
+	case *tls.Conn:
+		return connOf(c.NetConn())
+	}
+
+	return nil
+}
+
+// withoutValues is a context without the values of its parent, so that
+// the connections of resolvers are not reported to the client trace.
+type withoutValues struct {
+	context.Context
+}
+
+// Value implementation.
+func (withoutValues) Value(key any) any {
+	return nil
+}
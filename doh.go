@@ -0,0 +1,127 @@
+package httpstat
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// NewDoHResolver returns a resolver querying the DNS-over-HTTPS (RFC 8484)
+// server at url, such as "https://dns.google/dns-query". The connection is
+// reused across lookups, so only the first reports its connection timing.
+func NewDoHResolver(url string, config *tls.Config) Resolver {
+	return &dohResolver{
+		url: url,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     config,
+				ForceAttemptHTTP2:   true,
+				MaxConnsPerHost:     1,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+		},
+	}
+}
+
+// dohResolver is a DNS-over-HTTPS server.
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+// Resolve implementation.
+func (d *dohResolver) Resolve(ctx context.Context, host string) (*DNS, error) {
+	return resolve(ctx, d, host)
+}
+
+// exchange implementation.
+func (d *dohResolver) exchange(ctx context.Context, m *dns.Msg, q *DNSQuery) (*dns.Msg, error) {
+	q.Server = d.url
+
+	// the ID is zero for HTTP caches, see RFC 8484 section 4.1
+	m.Id = 0
+
+	b, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	// hooks may be called after the request returns,
+	// so timings are copied to q once it completes
+	var mu sync.Mutex
+	var connectStart, tlsStart time.Time
+	var connect, handshake time.Duration
+
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			connectStart = time.Now()
+			mu.Unlock()
+		},
+
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			connect = time.Since(connectStart)
+			mu.Unlock()
+		},
+
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			mu.Lock()
+			handshake = time.Since(tlsStart)
+			mu.Unlock()
+		},
+	})
+
+	defer func() {
+		mu.Lock()
+		q.Connect, q.TLS = connect, handshake
+		mu.Unlock()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server responded with %s", res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,129 @@
+package httpstat
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// NewDoTResolver returns a resolver querying the DNS-over-TLS (RFC 7858)
+// server at addr, defaulting to port 853. The connection is reused
+// across lookups, so only the first reports its connection timing.
+func NewDoTResolver(addr string, config *tls.Config) Resolver {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "853")
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+
+	return &dotResolver{
+		addr:   addr,
+		config: config,
+	}
+}
+
+// dotResolver is a DNS-over-TLS server.
+type dotResolver struct {
+	addr   string
+	config *tls.Config
+
+	mu   sync.Mutex
+	conn *dns.Conn
+}
+
+// Resolve implementation.
+func (d *dotResolver) Resolve(ctx context.Context, host string) (*DNS, error) {
+	return resolve(ctx, d, host)
+}
+
+// exchange implementation. Queries are made one at a time, retrying
+// once on a new connection when a reused connection fails.
+func (d *dotResolver) exchange(ctx context.Context, m *dns.Msg, q *DNSQuery) (*dns.Msg, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	q.Server = "tls://" + d.addr
+
+	for {
+		reused := d.conn != nil
+
+		if !reused {
+			if err := d.connect(ctx, q); err != nil {
+				return nil, err
+			}
+		}
+
+		r, err := d.roundTrip(ctx, m)
+		if err == nil {
+			return r, nil
+		}
+
+		d.conn.Close()
+		d.conn = nil
+
+		if !reused {
+			return nil, err
+		}
+	}
+}
+
+// connect to the server.
+func (d *dotResolver) connect(ctx context.Context, q *DNSQuery) error {
+	var dialer net.Dialer
+
+	start := time.Now()
+	c, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return err
+	}
+	q.Connect = time.Since(start)
+
+	config := d.config.Clone()
+	if config.ServerName == "" {
+		host, _, _ := net.SplitHostPort(d.addr)
+		config.ServerName = host
+	}
+
+	start = time.Now()
+	tc := tls.Client(c, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return err
+	}
+	q.TLS = time.Since(start)
+
+	d.conn = &dns.Conn{Conn: tc}
+
+	return nil
+}
+
+// roundTrip writes m and reads its response.
+func (d *dotResolver) roundTrip(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+
+	d.conn.SetDeadline(deadline)
+
+	if err := d.conn.WriteMsg(m); err != nil {
+		return nil, err
+	}
+
+	r, err := d.conn.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Id != m.Id {
+		return nil, dns.ErrId
+	}
+
+	return r, nil
+}
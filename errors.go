@@ -17,7 +17,11 @@ import (
 // Errors.
 var (
 	ErrMaxRedirectsExceeded = errors.New("max redirects exceeded")
+	ErrRedirectCrossOrigin  = errors.New("cross-origin redirect not allowed")
+	ErrRedirectDowngrade    = errors.New("redirect from HTTPS to HTTP not allowed")
+	ErrRedirectLoop         = errors.New("redirect loop")
 	ErrTimeoutExceeded      = errors.New("timeout exceeded")
+	ErrHTTP2NotSupported    = errors.New("HTTP/2 not supported")
 )
 
 // Normalize the given error.
@@ -43,10 +47,22 @@ func urlError(err *url.Error) error {
 		return ErrMaxRedirectsExceeded
 	}
 
+	if err, ok := err.Err.(*RedirectLoopError); ok {
+		return err
+	}
+
+	if err.Err == ErrHTTP2NotSupported {
+		return ErrHTTP2NotSupported
+	}
+
 	if err, ok := err.Err.(*net.OpError); ok {
 		return opError(err)
 	}
 
+	if err, ok := err.Err.(socksReply); ok {
+		return socksError(err)
+	}
+
 	if _, ok := err.Err.(tls.RecordHeaderError); ok {
 		return errors.New("invalid TLS record header")
 	}
@@ -90,6 +106,30 @@ func certError(err x509.CertificateInvalidError) error {
 	return errors.New(strings.Replace(err.Error(), "x509: ", "SSL ", 1))
 }
 
+// SOCKS error.
+func socksError(err socksReply) error {
+	switch err {
+	case 0x01:
+		return errors.New("SOCKS general server failure")
+	case 0x02:
+		return errors.New("SOCKS connection not allowed by ruleset")
+	case 0x03:
+		return errors.New("SOCKS network unreachable")
+	case 0x04:
+		return errors.New("SOCKS host unreachable")
+	case 0x05:
+		return errors.New("SOCKS connection refused")
+	case 0x06:
+		return errors.New("SOCKS TTL expired")
+	case 0x07:
+		return errors.New("SOCKS command not supported")
+	case 0x08:
+		return errors.New("SOCKS address type not supported")
+	}
+
+	return err
+}
+
 // Op error.
 func opError(err *net.OpError) error {
 	if err, ok := err.Err.(*os.SyscallError); ok {
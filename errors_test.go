@@ -1,6 +1,7 @@
 package httpstat_test
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"testing"
@@ -13,6 +14,14 @@ import (
 
 func TestResponse_errors(t *testing.T) {
 	t.Run("max redirects exceeded", func(t *testing.T) {
+		s := server(chain(10))
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL+"/0", nil, nil)
+		assert.Equal(t, httpstat.ErrMaxRedirectsExceeded, err)
+	})
+
+	t.Run("redirect loop", func(t *testing.T) {
 		s := server(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Location", "/")
 			w.WriteHeader(302)
@@ -20,7 +29,36 @@ func TestResponse_errors(t *testing.T) {
 		defer s.Close()
 
 		_, err := httpstat.Request("GET", s.URL, nil, nil)
-		assert.EqualError(t, err, "max redirects exceeded")
+		assert.EqualError(t, err, "redirect loop: "+s.URL+"/ -> "+s.URL+"/")
+		assert.True(t, errors.Is(err, httpstat.ErrRedirectLoop), "redirect loop")
+	})
+
+	t.Run("redirect cycle", func(t *testing.T) {
+		s := server(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/a":
+				w.Header().Set("Location", "/b")
+			case "/b":
+				w.Header().Set("Location", "/c")
+			default:
+				w.Header().Set("Location", "/a")
+			}
+			w.WriteHeader(302)
+		})
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL+"/a", nil, nil)
+
+		e, ok := err.(*httpstat.RedirectLoopError)
+		assert.True(t, ok, "redirect loop")
+		assert.Equal(t, []string{s.URL + "/a", s.URL + "/b", s.URL + "/c", s.URL + "/a"}, e.Cycle)
+
+		_, err = httpstat.Request("GET", s.URL+"/a", nil, nil, httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{MaxRedirects: 10}))
+
+		var r *httpstat.RedirectError
+		assert.True(t, errors.As(err, &r), "redirect error")
+		assert.True(t, errors.As(err, &e), "redirect loop")
+		assert.Len(t, r.Traces, 3)
 	})
 
 	t.Run("tcp connection reset", func(t *testing.T) {
@@ -1,5 +1,24 @@
 module github.com/apex/httpstat
 
-go 1.14
+go 1.25.0
 
-require github.com/tj/assert v0.0.2
+require (
+	github.com/miekg/dns v1.1.72
+	github.com/quic-go/quic-go v0.59.1
+	github.com/tj/assert v0.0.2
+	golang.org/x/crypto v0.54.0
+	golang.org/x/net v0.57.0
+	golang.org/x/sys v0.47.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/quic-go/qpack v0.6.0 // indirect
+	github.com/stretchr/testify v1.11.1 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.22.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	golang.org/x/tools v0.47.0 // indirect
+)
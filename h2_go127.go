@@ -0,0 +1,7 @@
+//go:build go1.27
+
+package httpstat
+
+// tlsHTTP2 is true when the transport speaks HTTP/2 over TLS connections
+// other than *tls.Conn, such as those of the dialer, given their state.
+const tlsHTTP2 = true
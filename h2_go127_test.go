@@ -0,0 +1,48 @@
+//go:build go1.27
+
+package httpstat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+func TestResponse_HTTP2(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(noRedirects))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	t.Run("with default", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithTLSConfig(rootCAs(s)))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/2.0", res.Proto())
+		assert.Equal(t, "HTTP/2.0", res.Traces()[0].Proto())
+		assert.True(t, res.TLS(), "tls")
+		assert.NotZero(t, res.TimeTLS(), "tls")
+		assert.NotZero(t, res.HeaderSizeCompressed(), "header size compressed")
+		assert.Equal(t, 11, res.BodySize(), "body size")
+	})
+
+	t.Run("with HTTP/2", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithTLSConfig(rootCAs(s)), httpstat.WithProtocol(httpstat.ProtocolHTTP2))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/2.0", res.Proto())
+		assert.NotZero(t, res.TimeTLS(), "tls")
+	})
+
+	t.Run("with HTTP/1.1", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithTLSConfig(rootCAs(s)), httpstat.WithProtocol(httpstat.ProtocolHTTP1))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+		assert.Zero(t, res.HeaderSizeCompressed(), "header size compressed")
+	})
+}
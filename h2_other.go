@@ -0,0 +1,8 @@
+//go:build !go1.27
+
+package httpstat
+
+// tlsHTTP2 is true when the transport speaks HTTP/2 over TLS connections
+// other than *tls.Conn, such as those of the dialer, which it only does
+// as of Go 1.27, so HTTP/2 is not negotiated over TLS before it.
+const tlsHTTP2 = false
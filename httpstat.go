@@ -4,24 +4,118 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net/http"
 	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
 	"time"
 )
 
 // TODO: TimeResponse etc should have the end time stored,
 // currently only relevant to the final request
 
-// TODO: store more request information for redirects,
-// such as the url :)
+// Informational is an informational (1xx) response,
+// such as 100 Continue or 103 Early Hints.
+type Informational struct {
+	// Status code of the response.
+	Status int `json:"status"`
+
+	// Header of the response.
+	Header http.Header `json:"header,omitempty"`
+
+	// Time of arrival relative to the start of the trace.
+	Time time.Duration `json:"time"`
+}
+
+// Override is an address override applied when dialing.
+type Override struct {
+	// From is the address of the request.
+	From string `json:"from"`
+
+	// To is the address dialed in its place.
+	To string `json:"to"`
+
+	// BypassedDNS is true when To is an IP address, so no lookup was made.
+	BypassedDNS bool `json:"bypassed_dns"`
+}
+
+// Proxy is the proxy a request was made through. The address, DNS and
+// connect timings of the trace are those of the proxy, while the TLS
+// timing is that of the origin when a tunnel was established.
+type Proxy struct {
+	// URL of the proxy, with any password redacted.
+	URL string `json:"url"`
+
+	// Status of the response to the CONNECT request, when a
+	// tunnel was established to an HTTPS origin.
+	Status int `json:"status,omitempty"`
+
+	// TimeTLS is the time taken by the TLS handshake with an HTTPS proxy.
+	TimeTLS time.Duration `json:"time_tls,omitempty"`
+
+	// TimeTunnel is the time taken by the CONNECT request.
+	TimeTunnel time.Duration `json:"time_tunnel,omitempty"`
+
+	// tunnel is the proxy the dialer tunnels through to an HTTPS origin.
+	tunnel *url.URL
+}
+
+// TCPInfo is the kernel TCP information of a connection, captured
+// once the response is read. It is only available on Linux.
+type TCPInfo struct {
+	// RTT is the smoothed round trip time.
+	RTT time.Duration `json:"rtt"`
+
+	// RTTVar is the round trip time variance.
+	RTTVar time.Duration `json:"rtt_var"`
+
+	// Retransmits is the total number of segments retransmitted.
+	Retransmits int `json:"retransmits"`
+
+	// CongestionWindow is the send congestion window in segments.
+	CongestionWindow int `json:"congestion_window"`
+
+	// MSS is the send maximum segment size in bytes.
+	MSS int `json:"mss"`
+
+	// DeliveryRate is the most recent delivery rate in bytes per second.
+	DeliveryRate uint64 `json:"delivery_rate"`
+}
 
 // Trace results.
 type Trace interface {
+	URL() string
+	Status() int
+	Header() http.Header
+	Cookies() []*http.Cookie
 	Address() string
+	LocalAddr() string
+	Proxy() *Proxy
+	Override() *Override
+	DNS() *DNS
+	TCPInfo() *TCPInfo
+	Revocation() *Revocation
+	Proto() string
 	TLS() bool
+	ClientCertRequested() bool
+	ClientCertSent() bool
+	Resumed() bool
+	Used0RTT() bool
 	Start() time.Time
+	HeaderSize() int
+	HeaderSizeCompressed() int
+	RequestHeaderSize() int
+	RequestHeaderSizeCompressed() int
+	RequestBodySize() int
+	WriteError() error
+	Informational() []Informational
 	TimeDNS() time.Duration
 	TimeConnect() time.Duration
+	TimeSOCKS() time.Duration
 	TimeTLS() time.Duration
+	TimeSend() time.Duration
+	TimeUpload() time.Duration
+	TimeContinue() time.Duration
 	TimeWait() time.Duration
 	TimeResponse(time.Time) time.Duration
 	TimeDownload(time.Time) time.Duration
@@ -30,18 +124,87 @@ type Trace interface {
 }
 
 type trace struct {
-	addr string
-	tls  bool
+	url       string
+	status    int
+	header    http.Header
+	addr      string
+	localAddr string
+	tls       bool
+	resumed   bool
+	proxy     *Proxy
+	override  *Override
+	dns       *DNS
+	conn      *conn
+	quic      *quicConn
+	bodySize  int
+	writeErr  error
+	info      []Informational
+
+	clientCertRequested bool
+	clientCertSent      bool
+
+	revocationMode RevocationMode
+	revocation     func() *Revocation
 
-	start     time.Time
-	dnsStart  time.Time
-	dnsEnd    time.Time
-	tcpStart  time.Time
-	tcpEnd    time.Time
-	tlsStart  time.Time
-	tlsEnd    time.Time
-	waitStart time.Time
-	waitEnd   time.Time
+	start         time.Time
+	dnsStart      time.Time
+	dnsEnd        time.Time
+	tcpStart      time.Time
+	tcpEnd        time.Time
+	socksStart    time.Time
+	socksEnd      time.Time
+	tlsStart      time.Time
+	tlsEnd        time.Time
+	sendStart     time.Time
+	sendEnd       time.Time
+	continueStart time.Time
+	continueEnd   time.Time
+	waitStart     time.Time
+	waitEnd       time.Time
+}
+
+// traceKey is the context key of the current trace.
+type traceKey struct{}
+
+// traceFromContext returns the trace of the current connection, or nil.
+func traceFromContext(ctx context.Context) *trace {
+	if t, ok := ctx.Value(traceKey{}).(**trace); ok {
+		return *t
+	}
+
+	return nil
+}
+
+// proxyKey is the context key of the proxy of the next connection.
+type proxyKey struct{}
+
+// traceProxy returns a transport proxy function which records
+// the proxy chosen by fn for the connection of each request.
+func traceProxy(fn func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		var u *url.URL
+		var err error
+
+		if _, ok := unixSocket(req.Context(), req.URL.Host); !ok {
+			u, err = fn(req)
+		}
+
+		if p, ok := req.Context().Value(proxyKey{}).(**Proxy); ok {
+			*p = nil
+			if u != nil {
+				*p = &Proxy{URL: u.Redacted()}
+			}
+
+			// HTTPS origins are tunneled to by the dialer, so
+			// that the origin's plaintext stream is observed
+			if u != nil && req.URL.Scheme == "https" {
+				(*p).tunnel = u
+				return nil, err
+			}
+		}
+
+		return u, err
+	}
 }
 
 // TLS implementation.
@@ -49,16 +212,168 @@ func (t *trace) TLS() bool {
 	return t.tls
 }
 
+// ClientCertRequested implementation.
+func (t *trace) ClientCertRequested() bool {
+	return t.clientCertRequested
+}
+
+// ClientCertSent implementation.
+func (t *trace) ClientCertSent() bool {
+	return t.clientCertSent
+}
+
+// Resumed implementation.
+func (t *trace) Resumed() bool {
+	if t.quic != nil {
+		return t.quic.resumed()
+	}
+
+	return t.resumed
+}
+
+// Used0RTT implementation.
+func (t *trace) Used0RTT() bool {
+	return t.quic != nil && t.quic.used0RTT()
+}
+
+// Proto implementation.
+func (t *trace) Proto() string {
+	if t.quic != nil {
+		return "HTTP/3.0"
+	}
+
+	if t.conn == nil {
+		return ""
+	}
+
+	return t.conn.proto()
+}
+
+// URL implementation.
+func (t *trace) URL() string {
+	return t.url
+}
+
+// Status implementation.
+func (t *trace) Status() int {
+	return t.status
+}
+
+// Header implementation.
+func (t *trace) Header() http.Header {
+	return t.header
+}
+
+// Cookies implementation.
+func (t *trace) Cookies() []*http.Cookie {
+	return (&http.Response{Header: t.header}).Cookies()
+}
+
 // Address implementation.
 func (t *trace) Address() string {
 	return t.addr
 }
 
+// LocalAddr implementation.
+func (t *trace) LocalAddr() string {
+	return t.localAddr
+}
+
+// Proxy implementation.
+func (t *trace) Proxy() *Proxy {
+	return t.proxy
+}
+
+// Override implementation.
+func (t *trace) Override() *Override {
+	return t.override
+}
+
+// DNS implementation.
+func (t *trace) DNS() *DNS {
+	return t.dns
+}
+
+// TCPInfo implementation.
+func (t *trace) TCPInfo() *TCPInfo {
+	if t.conn == nil {
+		return nil
+	}
+
+	return t.conn.tcpInfo()
+}
+
+// Revocation implementation. It waits for any online checks to complete.
+func (t *trace) Revocation() *Revocation {
+	if t.revocation == nil {
+		return nil
+	}
+
+	return t.revocation()
+}
+
 // Start implementation.
 func (t *trace) Start() time.Time {
 	return t.start
 }
 
+// HeaderSize implementation. Header sizes are measured on connections
+// dialed by httpstat, and zero on others, such as those of HTTP/3 or
+// of clients given to RequestWithClient.
+func (t *trace) HeaderSize() int {
+	if t.conn == nil {
+		return 0
+	}
+
+	_, _, n, _ := t.conn.sizes()
+	return n
+}
+
+// HeaderSizeCompressed implementation.
+func (t *trace) HeaderSizeCompressed() int {
+	if t.conn == nil {
+		return 0
+	}
+
+	_, _, _, n := t.conn.sizes()
+	return n
+}
+
+// RequestHeaderSize implementation.
+func (t *trace) RequestHeaderSize() int {
+	if t.conn == nil {
+		return 0
+	}
+
+	n, _, _, _ := t.conn.sizes()
+	return n
+}
+
+// RequestHeaderSizeCompressed implementation.
+func (t *trace) RequestHeaderSizeCompressed() int {
+	if t.conn == nil {
+		return 0
+	}
+
+	_, n, _, _ := t.conn.sizes()
+	return n
+}
+
+// RequestBodySize implementation.
+func (t *trace) RequestBodySize() int {
+	return t.bodySize
+}
+
+// WriteError implementation.
+func (t *trace) WriteError() error {
+	return t.writeErr
+}
+
+// Informational implementation.
+func (t *trace) Informational() []Informational {
+	return t.info
+}
+
 // TimeDNS implementation.
 func (t *trace) TimeDNS() time.Duration {
 	return t.dnsEnd.Sub(t.dnsStart)
@@ -69,19 +384,70 @@ func (t *trace) TimeConnect() time.Duration {
 	return t.tcpEnd.Sub(t.tcpStart)
 }
 
+// TimeSOCKS implementation.
+func (t *trace) TimeSOCKS() time.Duration {
+	return t.socksEnd.Sub(t.socksStart)
+}
+
 // TimeTLS implementation.
 func (t *trace) TimeTLS() time.Duration {
+	// the QUIC handshake may complete after the
+	// request is sent, so it is recorded separately
+	if t.quic != nil {
+		end := t.quic.handshakeEnd()
+		if end.IsZero() {
+			return 0
+		}
+		return end.Sub(t.tlsStart)
+	}
+
 	return t.tlsEnd.Sub(t.tlsStart)
 }
 
+// TimeSend implementation.
+func (t *trace) TimeSend() time.Duration {
+	return t.sendEnd.Sub(t.sendStart)
+}
+
+// TimeUpload implementation.
+func (t *trace) TimeUpload() time.Duration {
+	if !t.continueEnd.IsZero() {
+		return t.waitStart.Sub(t.continueEnd)
+	}
+
+	return t.waitStart.Sub(t.sendEnd)
+}
+
+// TimeContinue implementation.
+func (t *trace) TimeContinue() time.Duration {
+	if t.continueEnd.IsZero() {
+		return 0
+	}
+
+	return t.continueEnd.Sub(t.continueStart)
+}
+
 // TimeWait implementation.
 func (t *trace) TimeWait() time.Duration {
-	return t.waitEnd.Sub(t.waitStart)
+	return t.firstByte().Sub(t.waitStart)
 }
 
 // TimeDownload implementation.
 func (t *trace) TimeDownload(now time.Time) time.Duration {
-	return now.Sub(t.waitEnd)
+	return now.Sub(t.firstByte())
+}
+
+// firstByte returns the arrival time of the final response. The
+// transport reports the first byte of any informational response
+// instead, so the connection is consulted when one was received.
+func (t *trace) firstByte() time.Time {
+	if len(t.info) > 0 && t.conn != nil {
+		if v := t.conn.firstByte(); !v.IsZero() {
+			return v
+		}
+	}
+
+	return t.waitEnd
 }
 
 // TimeResponse implementation.
@@ -97,19 +463,30 @@ func (t *trace) TimeTotal(now time.Time) time.Duration {
 // WithTraces traces request timings.
 func WithTraces(ctx context.Context, traces *[]Trace) context.Context {
 	var t *trace
+	var proxy *Proxy
+
+	ctx = context.WithValue(ctx, traceKey{}, &t)
+	ctx = context.WithValue(ctx, proxyKey{}, &proxy)
 
 	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
 		GetConn: func(addr string) {
 			t = &trace{}
 			t.start = time.Now()
 			t.addr = addr
+			t.proxy = proxy
 		},
 
 		GotConn: func(info httptrace.GotConnInfo) {
 			if info.Reused {
 				t = &trace{}
 				t.start = time.Now()
+				if proxy != nil {
+					t.proxy = &Proxy{URL: proxy.URL}
+				}
 			}
+			t.conn = connOf(info.Conn)
+			t.localAddr = info.Conn.LocalAddr().String()
+			t.sendStart = time.Now()
 			*traces = append(*traces, t)
 		},
 
@@ -129,17 +506,51 @@ func WithTraces(ctx context.Context, traces *[]Trace) context.Context {
 			t.tcpEnd = time.Now()
 		},
 
+		// the handshake is reported by the dialer, and
+		// again by the transport as of Go 1.27
 		TLSHandshakeStart: func() {
+			if !t.tlsEnd.IsZero() {
+				return
+			}
 			t.tls = true
 			t.tlsStart = time.Now()
 		},
 
-		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			if !t.tlsEnd.IsZero() {
+				return
+			}
 			t.tlsEnd = time.Now()
+			t.resumed = cs.DidResume
+			if err == nil && t.revocationMode != RevocationOff {
+				t.revocation = checkRevocation(cs, t.revocationMode)
+			}
+		},
+
+		WroteHeaders: func() {
+			t.sendEnd = time.Now()
+		},
+
+		Wait100Continue: func() {
+			t.continueStart = time.Now()
+		},
+
+		Got100Continue: func() {
+			t.continueEnd = time.Now()
+		},
+
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			t.info = append(t.info, Informational{
+				Status: code,
+				Header: http.Header(header).Clone(),
+				Time:   time.Since(t.start),
+			})
+			return nil
 		},
 
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
 			t.waitStart = time.Now()
+			t.writeErr = info.Err
 		},
 
 		GotFirstResponseByte: func() {
@@ -153,17 +564,51 @@ func (t trace) Stats() *Stats {
 	now := time.Now()
 
 	return &Stats{
-		TLS:          t.TLS(),
-		TimeDNS:      t.TimeDNS(),
-		TimeConnect:  t.TimeConnect(),
-		TimeTLS:      t.TimeTLS(),
-		TimeWait:     t.TimeWait(),
-		TimeResponse: t.TimeResponse(now),
-		TimeDownload: t.TimeDownload(now),
-		TimeTotal:    t.TimeTotal(now),
+		URL:                         t.URL(),
+		Status:                      t.Status(),
+		Header:                      t.Header(),
+		LocalAddr:                   t.LocalAddr(),
+		Proxy:                       t.Proxy(),
+		Override:                    t.Override(),
+		DNS:                         t.DNS(),
+		TCPInfo:                     t.TCPInfo(),
+		Revocation:                  t.Revocation(),
+		Proto:                       t.Proto(),
+		TLS:                         t.TLS(),
+		ClientCertRequested:         t.ClientCertRequested(),
+		ClientCertSent:              t.ClientCertSent(),
+		Resumed:                     t.Resumed(),
+		Used0RTT:                    t.Used0RTT(),
+		HeaderSize:                  t.HeaderSize(),
+		HeaderSizeCompressed:        t.HeaderSizeCompressed(),
+		RequestHeaderSize:           t.RequestHeaderSize(),
+		RequestHeaderSizeCompressed: t.RequestHeaderSizeCompressed(),
+		RequestBodySize:             t.RequestBodySize(),
+		WriteError:                  errorString(t.WriteError()),
+		Informational:               t.Informational(),
+		TimeDNS:                     t.TimeDNS(),
+		TimeConnect:                 t.TimeConnect(),
+		TimeSOCKS:                   t.TimeSOCKS(),
+		TimeTLS:                     t.TimeTLS(),
+		TimeSend:                    t.TimeSend(),
+		TimeUpload:                  t.TimeUpload(),
+		TimeContinue:                t.TimeContinue(),
+		TimeWait:                    t.TimeWait(),
+		TimeResponse:                t.TimeResponse(now),
+		TimeDownload:                t.TimeDownload(now),
+		TimeTotal:                   t.TimeTotal(now),
 	}
 }
 
+// errorString returns the message of err, or an empty string.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
 // Millisecond formatter.
 func ms(d time.Duration) string {
 	return fmt.Sprintf("%.0fms", float64(d)/float64(time.Millisecond))
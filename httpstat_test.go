@@ -1,12 +1,24 @@
 package httpstat_test
 
 import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/tj/assert"
 
 	"github.com/apex/httpstat"
@@ -299,3 +311,698 @@ func TestResponse_TLS(t *testing.T) {
 		assert.Equal(t, true, res.TLS())
 	})
 }
+
+func TestResponse_HeaderSize(t *testing.T) {
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "listen")
+	defer sock.Close()
+
+	head := "HTTP/1.1 200 OK\r\nContent-Length: 11\r\nX-Foo:   bar\r\n\r\n"
+	sent := make(chan int, 1)
+
+	go func() {
+		conn, err := sock.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		n := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n += len(line)
+			if line == "\r\n" {
+				break
+			}
+		}
+		sent <- n
+
+		conn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\n"))
+		conn.Write([]byte(head + "hello world"))
+	}()
+
+	res, err := httpstat.Request("GET", "http://"+sock.Addr().String(), nil, nil)
+	assert.NoError(t, err, "request")
+
+	assert.Equal(t, len(head), res.HeaderSize(), "header size")
+	assert.Equal(t, 0, res.HeaderSizeCompressed(), "header size compressed")
+	assert.Equal(t, <-sent, res.RequestHeaderSize(), "request header size")
+	assert.Equal(t, 11, res.BodySize(), "body size")
+}
+
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.r.Read(b)
+}
+
+func TestResponse_TimeUpload(t *testing.T) {
+	t.Run("with body", func(t *testing.T) {
+		var val []byte
+
+		s := server(func(w http.ResponseWriter, r *http.Request) {
+			val, _ = io.ReadAll(r.Body)
+		})
+		defer s.Close()
+
+		body := &slowReader{strings.NewReader("hello world"), 25 * time.Millisecond}
+		res, err := httpstat.Request("POST", s.URL, nil, body)
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "hello world", string(val))
+		assert.Equal(t, 11, res.RequestBodySize(), "request body size")
+		assert.NoError(t, res.Traces()[0].WriteError(), "write error")
+		assert.NoError(t, res.WriteError(), "write error")
+		assert.Empty(t, res.Stats().WriteError, "write error")
+		assertDuration(t, 50*time.Millisecond, res.TimeUpload())
+		assert.True(t, res.TimeSend() < res.TimeUpload(), "send")
+	})
+
+	t.Run("with redirects", func(t *testing.T) {
+		s := server(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			if r.URL.Path == "/" {
+				w.Header().Set("Location", "/bar")
+				w.WriteHeader(307)
+			}
+		})
+		defer s.Close()
+
+		res, err := httpstat.Request("PUT", s.URL, nil, strings.NewReader("hello world"))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 1, res.Redirects(), "redirects")
+		assert.Equal(t, 11, res.Traces()[0].RequestBodySize(), "request body size")
+		assert.Equal(t, 11, res.RequestBodySize(), "request body size")
+	})
+
+	t.Run("without body", func(t *testing.T) {
+		s := server(noRedirects)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 0, res.RequestBodySize(), "request body size")
+	})
+}
+
+func TestResponse_TimeContinue(t *testing.T) {
+	t.Run("with expect", func(t *testing.T) {
+		var val []byte
+
+		s := server(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(25 * time.Millisecond)
+			val, _ = io.ReadAll(r.Body)
+		})
+		defer s.Close()
+
+		header := http.Header{"Expect": []string{"100-continue"}}
+		res, err := httpstat.Request("POST", s.URL, header, strings.NewReader("hello world"))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "hello world", string(val))
+		assertDuration(t, 25*time.Millisecond, res.TimeContinue())
+		assert.Len(t, res.Informational(), 1)
+		assert.Equal(t, 100, res.Informational()[0].Status, "status")
+		assert.True(t, res.TimeWait() >= 0, "wait")
+	})
+
+	t.Run("without expect", func(t *testing.T) {
+		s := server(noRedirects)
+		defer s.Close()
+
+		res, err := httpstat.Request("POST", s.URL, nil, strings.NewReader("hello world"))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, time.Duration(0), res.TimeContinue())
+		assert.Empty(t, res.Informational())
+	})
+}
+
+func TestResponse_Informational(t *testing.T) {
+	s := server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(103)
+		time.Sleep(25 * time.Millisecond)
+		w.Header().Del("Link")
+		w.Write([]byte("hello world"))
+	})
+	defer s.Close()
+
+	res, err := httpstat.Request("GET", s.URL, nil, nil)
+	assert.NoError(t, err, "request")
+
+	info := res.Informational()
+	assert.Len(t, info, 1)
+	assert.Equal(t, 103, info[0].Status, "status")
+	assert.Equal(t, "</style.css>; rel=preload; as=style", info[0].Header.Get("Link"))
+	assert.True(t, info[0].Time < res.TimeTotal(time.Now()), "time")
+	assertDuration(t, 25*time.Millisecond, res.TimeWait())
+	assert.Empty(t, res.Header().Get("Link"))
+}
+
+func TestResponse_Proto(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(noRedirects))
+	s.Config.Protocols = new(http.Protocols)
+	s.Config.Protocols.SetHTTP1(true)
+	s.Config.Protocols.SetUnencryptedHTTP2(true)
+	s.Start()
+	defer s.Close()
+
+	t.Run("with default", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+		assert.Equal(t, "HTTP/1.1", res.Traces()[0].Proto())
+		assert.Equal(t, 0, res.HeaderSizeCompressed())
+	})
+
+	t.Run("with HTTP/1.1", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithProtocol(httpstat.ProtocolHTTP1))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+	})
+
+	t.Run("with h2c", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithProtocol(httpstat.ProtocolH2C))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Equal(t, "HTTP/2.0", res.Proto())
+		assert.Len(t, res.Traces(), 1)
+		assert.Equal(t, "HTTP/2.0", res.Traces()[0].Proto())
+		assert.Equal(t, 11, res.BodySize(), "body size")
+		assert.NotZero(t, res.HeaderSizeCompressed(), "header size compressed")
+		assert.True(t, res.HeaderSize() > res.HeaderSizeCompressed(), "header size")
+		assert.NotZero(t, res.RequestHeaderSizeCompressed(), "request header size compressed")
+		assert.True(t, res.RequestHeaderSize() > res.RequestHeaderSizeCompressed(), "request header size")
+
+		// ":status", "content-type", "content-length" and "date"
+		assert.Equal(t, len(":status200content-typetext/plain; charset=utf-8content-length11date")+len(http.TimeFormat), res.HeaderSize(), "header size")
+
+		// ":authority", ":method", ":path", ":scheme" and "user-agent"
+		host := strings.TrimPrefix(s.URL, "http://")
+		assert.Equal(t, len(":authority"+host+":methodGET:path/:schemehttpuser-agentGo-http-client/2.0"), res.RequestHeaderSize(), "request header size")
+		assertDuration(t, 25*time.Millisecond, res.TimeWait())
+	})
+}
+
+func TestResponse_ProtoTLS(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+	defer s.Close()
+
+	t.Run("with default", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithTLSConfig(rootCAs(s)))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+		assert.True(t, res.TLS(), "tls")
+		assert.NotZero(t, res.TimeTLS(), "tls")
+	})
+
+	t.Run("with HTTP/2 not supported", func(t *testing.T) {
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithTLSConfig(rootCAs(s)), httpstat.WithProtocol(httpstat.ProtocolHTTP2))
+		assert.Equal(t, httpstat.ErrHTTP2NotSupported, err)
+	})
+
+	t.Run("with HTTP/2 over HTTP", func(t *testing.T) {
+		s := server(noRedirects)
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithProtocol(httpstat.ProtocolHTTP2))
+		assert.Equal(t, httpstat.ErrHTTP2NotSupported, err)
+	})
+}
+
+// quicServer serves h over HTTP/3 with the certificate of s.
+func quicServer(t testing.TB, s *httptest.Server, h http.HandlerFunc) (*http3.Server, int) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err, "listen")
+
+	qs := &http3.Server{
+		Handler:    h,
+		TLSConfig:  http3.ConfigureTLSConfig(s.TLS.Clone()),
+		QUICConfig: &quic.Config{Allow0RTT: true},
+	}
+
+	go qs.Serve(pc)
+
+	return qs, pc.LocalAddr().(*net.UDPAddr).Port
+}
+
+// rootCAs returns a TLS config trusting the certificate of s.
+func rootCAs(s *httptest.Server) *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	return &tls.Config{RootCAs: pool}
+}
+
+func TestResponse_HTTP3(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+	defer s.Close()
+
+	qs, port := quicServer(t, s, noRedirects)
+	defer qs.Close()
+
+	uri := fmt.Sprintf("https://127.0.0.1:%d", port)
+
+	t.Run("with HTTP/3", func(t *testing.T) {
+		c := httpstat.NewClient(httpstat.WithProtocol(httpstat.ProtocolHTTP3), httpstat.WithTLSConfig(rootCAs(s)))
+
+		res, err := httpstat.RequestWithClient(c, "GET", uri, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Equal(t, "HTTP/3.0", res.Proto())
+		assert.Len(t, res.Traces(), 1)
+		assert.True(t, res.TLS(), "tls")
+		assert.False(t, res.Used0RTT(), "0-RTT")
+		assert.Equal(t, 11, res.BodySize(), "body size")
+		assert.Zero(t, res.HeaderSize(), "header size")
+		assert.Zero(t, res.TimeConnect(), "connect")
+		assert.NotZero(t, res.TimeTLS(), "tls")
+		assertDuration(t, 25*time.Millisecond, res.TimeWait())
+
+		res, err = httpstat.RequestWithClient(c, "GET", uri, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.True(t, res.Used0RTT(), "0-RTT")
+		assert.True(t, res.Stats().Used0RTT, "0-RTT")
+	})
+
+	t.Run("with Alt-Svc", func(t *testing.T) {
+		var port int
+
+		s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=60`, port))
+			http.Redirect(w, r, "/bar", http.StatusFound)
+		}))
+		defer s.Close()
+
+		qs, p := quicServer(t, s, redirects)
+		defer qs.Close()
+		port = p
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithAltSvc(true), httpstat.WithTLSConfig(rootCAs(s)))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Len(t, res.Traces(), 3)
+		assert.Equal(t, "HTTP/1.1", res.Traces()[0].Proto())
+		assert.Equal(t, "HTTP/3.0", res.Traces()[1].Proto())
+		assert.Equal(t, "HTTP/3.0", res.Traces()[2].Proto())
+		assert.Equal(t, "HTTP/3.0", res.Proto())
+		assert.Equal(t, 11, res.BodySize(), "body size")
+	})
+
+	t.Run("without Alt-Svc", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithAltSvc(true), httpstat.WithTLSConfig(rootCAs(s)))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+	})
+}
+
+func TestResponse_Override(t *testing.T) {
+	host := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}
+
+	t.Run("with an IP address", func(t *testing.T) {
+		s := server(host)
+		defer s.Close()
+
+		_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+		from := net.JoinHostPort("example.com", port)
+
+		res, err := httpstat.Request("GET", "http://"+from, nil, nil, httpstat.WithResolve(map[string]string{
+			from: "127.0.0.1",
+		}))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Equal(t, len(from), res.BodySize(), "host")
+		assert.Equal(t, &httpstat.Override{
+			From:        from,
+			To:          s.Listener.Addr().String(),
+			BypassedDNS: true,
+		}, res.Traces()[0].Override())
+		assert.Zero(t, res.TimeDNS(), "dns")
+	})
+
+	t.Run("with a port", func(t *testing.T) {
+		s := httptest.NewTLSServer(http.HandlerFunc(host))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", "https://EXAMPLE.com", nil, nil, httpstat.WithTLSConfig(rootCAs(s)), httpstat.WithResolve(map[string]string{
+			"example.com:443": s.Listener.Addr().String(),
+		}))
+		assert.NoError(t, err, "request")
+
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.True(t, res.TLS(), "tls")
+		assert.Equal(t, s.Listener.Addr().String(), res.Stats().Traces[0].Override.To)
+	})
+
+	t.Run("without a match", func(t *testing.T) {
+		s := server(host)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithResolve(map[string]string{
+			"example.com:80": "127.0.0.1",
+		}))
+		assert.NoError(t, err, "request")
+
+		assert.Nil(t, res.Traces()[0].Override())
+	})
+}
+
+// dualStackServer serves the address family of each request
+// on both loopback addresses, delaying IPv6 responses.
+func dualStackServer(t testing.TB) string {
+	l4, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err, "listen")
+
+	_, port, _ := net.SplitHostPort(l4.Addr().String())
+	l6, err := net.Listen("tcp6", net.JoinHostPort("::1", port))
+	assert.NoError(t, err, "listen")
+
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			if net.ParseIP(host).To4() != nil {
+				w.Write([]byte("ipv4"))
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+			w.Write([]byte("ipv6!"))
+		}),
+	}
+
+	go s.Serve(l4)
+	go s.Serve(l6)
+	t.Cleanup(func() { s.Close() })
+
+	return port
+}
+
+func TestResponse_Family(t *testing.T) {
+	port := dualStackServer(t)
+	resolver := httpstat.WithResolver(httpstat.NewResolver("udp", dnsServer(t, "udp", nil)))
+	uri := "http://" + net.JoinHostPort("dual.example.test", port)
+
+	t.Run("with IPv4", func(t *testing.T) {
+		res, err := httpstat.Request("GET", uri, nil, nil, resolver, httpstat.WithFamily(httpstat.FamilyIPv4))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 4, res.BodySize(), "body size")
+	})
+
+	t.Run("with IPv6", func(t *testing.T) {
+		res, err := httpstat.Request("GET", uri, nil, nil, resolver, httpstat.WithFamily(httpstat.FamilyIPv6))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 5, res.BodySize(), "body size")
+	})
+
+	t.Run("with an IP address", func(t *testing.T) {
+		_, err := httpstat.Request("GET", "http://127.0.0.1:"+port, nil, nil, httpstat.WithFamily(httpstat.FamilyIPv6))
+		assert.Error(t, err, "request")
+	})
+
+	t.Run("with dual stack", func(t *testing.T) {
+		v, err := httpstat.RequestDualStack("POST", uri, nil, strings.NewReader("hello"), resolver)
+		assert.NoError(t, err, "request")
+
+		assert.NoError(t, v.IPv4Err, "ipv4")
+		assert.NoError(t, v.IPv6Err, "ipv6")
+		assert.Equal(t, 4, v.IPv4.BodySize(), "ipv4 body size")
+		assert.Equal(t, 5, v.IPv6.BodySize(), "ipv6 body size")
+		assert.Equal(t, 5, v.IPv4.RequestBodySize(), "ipv4 request body size")
+		assert.Equal(t, 5, v.IPv6.RequestBodySize(), "ipv6 request body size")
+		assert.True(t, v.IPv6.TimeWait() > v.IPv4.TimeWait()+25*time.Millisecond, "wait")
+	})
+
+	t.Run("with a broken family", func(t *testing.T) {
+		s := server(noRedirects)
+		defer s.Close()
+
+		v, err := httpstat.RequestDualStack("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.NoError(t, v.IPv4Err, "ipv4")
+		assert.Equal(t, 200, v.IPv4.Status(), "status")
+		assert.Error(t, v.IPv6Err, "ipv6")
+		assert.Nil(t, v.IPv6)
+	})
+}
+
+func TestResponse_LocalAddr(t *testing.T) {
+	s := server(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		w.Write([]byte(host))
+	})
+	defer s.Close()
+
+	t.Run("with default", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		host, _, _ := net.SplitHostPort(res.Traces()[0].LocalAddr())
+		assert.Equal(t, "127.0.0.1", host)
+	})
+
+	t.Run("with a local address", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithLocalAddr(net.ParseIP("127.0.0.2")))
+		assert.NoError(t, err, "request")
+
+		host, _, _ := net.SplitHostPort(res.Traces()[0].LocalAddr())
+		assert.Equal(t, "127.0.0.2", host)
+		assert.Equal(t, len(host), res.BodySize(), "remote address")
+		assert.Equal(t, res.Traces()[0].LocalAddr(), res.Stats().Traces[0].LocalAddr)
+	})
+}
+
+// proxy forwards requests, tunneling CONNECT requests to their target.
+func proxy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "CONNECT" {
+		r.RequestURI = ""
+		res, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		w.WriteHeader(res.StatusCode)
+		io.Copy(w, res.Body)
+		return
+	}
+
+	origin, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer origin.Close()
+
+	c, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer c.Close()
+
+	time.Sleep(10 * time.Millisecond)
+	io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	go io.Copy(origin, c)
+	io.Copy(c, origin)
+}
+
+func TestResponse_Proxy(t *testing.T) {
+	s := server(noRedirects)
+	defer s.Close()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+	defer ts.Close()
+
+	p := server(proxy)
+	defer p.Close()
+
+	tp := httptest.NewTLSServer(http.HandlerFunc(proxy))
+	defer tp.Close()
+
+	request := func(t *testing.T, proxy *httptest.Server, uri string) httpstat.Response {
+		u, _ := url.Parse(proxy.URL)
+		u.User = url.UserPassword("tobi", "ferret")
+
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithProxy(u), httpstat.WithTLSConfig(rootCAs(ts)))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Equal(t, proxy.Listener.Addr().String(), res.Traces()[0].Address(), "address")
+		return res
+	}
+
+	t.Run("with an HTTP origin", func(t *testing.T) {
+		res := request(t, p, s.URL)
+
+		v := res.Traces()[0].Proxy()
+		assert.Equal(t, "http://tobi:xxxxx@"+p.Listener.Addr().String(), v.URL)
+		assert.Equal(t, 0, v.Status, "status")
+		assert.Zero(t, v.TimeTunnel, "tunnel")
+		assert.False(t, res.TLS(), "tls")
+		assert.Equal(t, "HTTP/1.1", res.Proto())
+	})
+
+	t.Run("with an HTTPS origin", func(t *testing.T) {
+		res := request(t, p, ts.URL)
+
+		v := res.Traces()[0].Proxy()
+		assert.Equal(t, 200, v.Status, "status")
+		assert.True(t, v.TimeTunnel >= 10*time.Millisecond, "tunnel")
+		assert.Zero(t, v.TimeTLS, "proxy tls")
+		assert.True(t, res.TLS(), "tls")
+		assert.NotZero(t, res.TimeTLS(), "tls")
+		assert.Equal(t, v, res.Stats().Traces[0].Proxy)
+
+		trace := res.Traces()[0]
+		assert.Equal(t, "HTTP/1.1", trace.Proto())
+		assert.NotZero(t, trace.HeaderSize(), "header size")
+		assert.NotZero(t, trace.RequestHeaderSize(), "request header size")
+		if runtime.GOOS == "linux" {
+			assert.NotNil(t, trace.TCPInfo(), "tcp info")
+		}
+	})
+
+	t.Run("with an HTTPS proxy", func(t *testing.T) {
+		res := request(t, tp, ts.URL)
+
+		v := res.Traces()[0].Proxy()
+		assert.Equal(t, "https://tobi:xxxxx@"+tp.Listener.Addr().String(), v.URL)
+		assert.Equal(t, 200, v.Status, "status")
+		assert.True(t, v.TimeTunnel >= 10*time.Millisecond, "tunnel")
+		assert.NotZero(t, v.TimeTLS, "proxy tls")
+		assert.True(t, res.TLS(), "tls")
+		assert.NotZero(t, res.TimeTLS(), "tls")
+
+		trace := res.Traces()[0]
+		assert.Equal(t, "HTTP/1.1", trace.Proto())
+		assert.NotZero(t, trace.HeaderSize(), "header size")
+		assert.NotZero(t, trace.RequestHeaderSize(), "request header size")
+		if runtime.GOOS == "linux" {
+			assert.NotNil(t, trace.TCPInfo(), "tcp info")
+		}
+	})
+
+	t.Run("with a tunnel refused", func(t *testing.T) {
+		p := server(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		defer p.Close()
+
+		u, _ := url.Parse(p.URL)
+		_, err := httpstat.Request("GET", ts.URL, nil, nil, httpstat.WithProxy(u), httpstat.WithTLSConfig(rootCAs(ts)))
+		assert.EqualError(t, err, "proxy responded with 403 Forbidden")
+	})
+
+	t.Run("without a proxy", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithProxy(nil))
+		assert.NoError(t, err, "request")
+		assert.Nil(t, res.Traces()[0].Proxy())
+	})
+}
+
+func TestResponse_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	l, err := net.Listen("unix", path)
+	assert.NoError(t, err, "listen")
+
+	s := &httptest.Server{
+		Listener: l,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host + r.URL.Path))
+		})},
+	}
+	s.Start()
+	defer s.Close()
+
+	t.Run("with a path", func(t *testing.T) {
+		res, err := httpstat.Request("GET", "unix://"+path+":/healthz", nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.Equal(t, len("localhost/healthz"), res.BodySize(), "body size")
+
+		trace := res.Traces()[0]
+		assert.Equal(t, path, trace.Address())
+		assert.Zero(t, trace.TimeDNS(), "dns")
+		assert.NotZero(t, trace.TimeConnect(), "connect")
+		assert.NotZero(t, res.HeaderSize(), "header size")
+	})
+
+	t.Run("without a path", func(t *testing.T) {
+		res, err := httpstat.Request("GET", "unix://"+path, nil, nil, httpstat.WithProxy(&url.URL{Scheme: "http", Host: "127.0.0.1:1"}))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, len("localhost/"), res.BodySize(), "body size")
+		assert.Nil(t, res.Traces()[0].Proxy())
+	})
+
+	t.Run("with a colon in the socket path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app:1.sock")
+
+		l, err := net.Listen("unix", path)
+		assert.NoError(t, err, "listen")
+
+		s := &httptest.Server{Listener: l, Config: &http.Server{Handler: s.Config.Handler}}
+		s.Start()
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", "unix://"+path+":/healthz", nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Equal(t, len("localhost/healthz"), res.BodySize(), "body size")
+		assert.Equal(t, path, res.Traces()[0].Address())
+
+		res, err = httpstat.Request("GET", "unix://"+path, nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Equal(t, len("localhost/"), res.BodySize(), "body size")
+	})
+
+	t.Run("with a URL in the query", func(t *testing.T) {
+		res, err := httpstat.Request("GET", "unix://"+path+":/healthz?next=http://x/y", nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Equal(t, len("localhost/healthz"), res.BodySize(), "body size")
+		assert.Equal(t, path, res.Traces()[0].Address())
+	})
+
+	t.Run("with a colon ending a directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "app:")
+		assert.NoError(t, os.Mkdir(dir, 0o700), "mkdir")
+		path := filepath.Join(dir, "app.sock")
+
+		l, err := net.Listen("unix", path)
+		assert.NoError(t, err, "listen")
+
+		s := &httptest.Server{Listener: l, Config: &http.Server{Handler: s.Config.Handler}}
+		s.Start()
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", "unix://"+path+":/healthz", nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Equal(t, len("localhost/healthz"), res.BodySize(), "body size")
+		assert.Equal(t, path, res.Traces()[0].Address())
+	})
+
+	t.Run("with a missing socket", func(t *testing.T) {
+		_, err := httpstat.Request("GET", "unix://"+path+".missing:/healthz", nil, nil)
+		assert.EqualError(t, err, "no such file or directory")
+	})
+}
@@ -0,0 +1,354 @@
+package httpstat
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol is an HTTP protocol selection.
+type Protocol int
+
+// Protocols available.
+const (
+	// ProtocolAuto negotiates HTTP/2 or HTTP/1.1 over TLS, using HTTP/1.1
+	// otherwise. HTTP/2 over TLS requires Go 1.27 or later.
+	ProtocolAuto Protocol = iota
+
+	// ProtocolHTTP1 uses HTTP/1.1 only.
+	ProtocolHTTP1
+
+	// ProtocolHTTP2 uses HTTP/2 over TLS only, failing with
+	// ErrHTTP2NotSupported for HTTP URLs, or when it is not negotiated.
+	ProtocolHTTP2
+
+	// ProtocolH2C uses unencrypted HTTP/2 with prior knowledge.
+	ProtocolH2C
+
+	// ProtocolHTTP3 uses HTTP/3 over QUIC only.
+	ProtocolHTTP3
+)
+
+// String implementation.
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolHTTP1:
+		return "http1"
+	case ProtocolHTTP2:
+		return "h2"
+	case ProtocolH2C:
+		return "h2c"
+	case ProtocolHTTP3:
+		return "h3"
+	default:
+		return "auto"
+	}
+}
+
+// ALPN protocols offered.
+func (p Protocol) nextProtos() []string {
+	switch p {
+	case ProtocolHTTP1:
+		return []string{"http/1.1"}
+	case ProtocolHTTP2:
+		return []string{"h2"}
+	case ProtocolAuto:
+		if !tlsHTTP2 {
+			return []string{"http/1.1"}
+		}
+		fallthrough
+	default:
+		return []string{"h2", "http/1.1"}
+	}
+}
+
+// Transport protocols.
+func (p Protocol) protocols() *http.Protocols {
+	var v http.Protocols
+
+	switch p {
+	case ProtocolHTTP1:
+		v.SetHTTP1(true)
+	case ProtocolHTTP2:
+		v.SetHTTP2(true)
+	case ProtocolH2C:
+		v.SetUnencryptedHTTP2(true)
+	default:
+		v.SetHTTP1(true)
+		v.SetHTTP2(true)
+	}
+
+	return &v
+}
+
+// Family is an IP address family selection.
+type Family int
+
+// Families available.
+const (
+	// FamilyAny uses either address family.
+	FamilyAny Family = iota
+
+	// FamilyIPv4 uses IPv4 only.
+	FamilyIPv4
+
+	// FamilyIPv6 uses IPv6 only.
+	FamilyIPv6
+)
+
+// String implementation.
+func (f Family) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "ipv4"
+	case FamilyIPv6:
+		return "ipv6"
+	default:
+		return "any"
+	}
+}
+
+// network returns the network of the family, such as "tcp4" for "tcp".
+func (f Family) network(network string) string {
+	switch f {
+	case FamilyIPv4:
+		return network + "4"
+	case FamilyIPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// match returns true if ip is of the family.
+func (f Family) match(ip net.IP) bool {
+	switch f {
+	case FamilyIPv4:
+		return ip.To4() != nil
+	case FamilyIPv6:
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
+
+// Option function.
+type Option func(*config)
+
+// config for clients.
+type config struct {
+	protocol  Protocol
+	altSvc    bool
+	tlsConfig *tls.Config
+	resolve   map[string]string
+	resolver  Resolver
+	family    Family
+	localAddr net.IP
+	iface     string
+	proxy     func(*http.Request) (*url.URL, error)
+	socks     *socks
+
+	clientCert func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	rootCAs    *x509.CertPool
+	serverName string
+	insecure   bool
+
+	sessionCache tls.ClientSessionCache
+	revocation   RevocationMode
+
+	redirect RedirectPolicy
+	jar      http.CookieJar
+	timeout  time.Duration
+}
+
+// newConfig returns a config with the given options applied.
+func newConfig(options []Option) *config {
+	c := &config{
+		proxy:   http.ProxyFromEnvironment,
+		timeout: 10 * time.Second,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// WithTimeout sets the time limit of requests, including
+// redirects and reading the body, defaulting to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithProtocol sets the HTTP protocol used, defaulting to ProtocolAuto.
+func WithProtocol(p Protocol) Option {
+	return func(c *config) {
+		c.protocol = p
+	}
+}
+
+// WithAltSvc enables upgrading to HTTP/3 for origins which advertise
+// it with the Alt-Svc response header. Advertisements are remembered
+// by the client, so redirects and later requests made with the same
+// client use HTTP/3, falling back when the QUIC connection fails.
+func WithAltSvc(enabled bool) Option {
+	return func(c *config) {
+		c.altSvc = enabled
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for connections.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithClientCertificate sets the client certificate sent when
+// requested by servers, for mutual TLS.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *config) {
+		c.clientCert = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert, nil
+		}
+	}
+}
+
+// WithClientCertificateFile sets the PEM encoded client certificate
+// and key files sent when requested by servers, for mutual TLS. The
+// files are loaded once requested, failing the request when invalid.
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(c *config) {
+		c.clientCert = loadClientCertificate(certFile, keyFile)
+	}
+}
+
+// WithRootCAs sets the root certificate authorities used to verify
+// servers in place of the system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *config) {
+		c.rootCAs = pool
+	}
+}
+
+// WithServerName sets the TLS server name (SNI) sent and verified in
+// place of the host of each request, including any redirects.
+func WithServerName(name string) Option {
+	return func(c *config) {
+		c.serverName = name
+	}
+}
+
+// WithInsecure disables the verification of server certificates.
+func WithInsecure(enabled bool) Option {
+	return func(c *config) {
+		c.insecure = enabled
+	}
+}
+
+// WithSessionCache sets the cache of TLS sessions resumed by later
+// connections. HTTP/3 clients use a cache of their own by default.
+func WithSessionCache(cache tls.ClientSessionCache) Option {
+	return func(c *config) {
+		c.sessionCache = cache
+	}
+}
+
+// WithRevocation sets the revocation checking of server certificates,
+// reported by the Revocation of each trace. Online checks are made in
+// the background so as not to affect timings. HTTP/3 is not checked.
+func WithRevocation(mode RevocationMode) Option {
+	return func(c *config) {
+		c.revocation = mode
+	}
+}
+
+// WithRedirectPolicy sets the policy of the redirects followed, in place
+// of following up to DefaultMaxRedirects. Requests stopped by the policy
+// fail with a RedirectError, wrapping errors such as ErrMaxRedirectsExceeded.
+func WithRedirectPolicy(p RedirectPolicy) Option {
+	return func(c *config) {
+		c.redirect = p
+	}
+}
+
+// WithCookieJar sets the cookie jar of the client, storing the cookies
+// set by each response, including redirects, and sending them with later
+// requests. Pass the same jar to several requests to share their cookies,
+// such as to log in and then request an authenticated page.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *config) {
+		c.jar = jar
+	}
+}
+
+// WithResolve overrides the addresses dialed, mapping "host:port" to an
+// "addr" or "addr:port" to dial in its place, similar to curl's --resolve
+// and --connect-to. The Host header and TLS server name are unchanged.
+func WithResolve(overrides map[string]string) Option {
+	return func(c *config) {
+		if c.resolve == nil {
+			c.resolve = make(map[string]string)
+		}
+
+		for from, to := range overrides {
+			if host, port, err := net.SplitHostPort(from); err == nil {
+				from = net.JoinHostPort(strings.ToLower(host), port)
+			}
+			c.resolve[from] = to
+		}
+	}
+}
+
+// WithResolver sets the resolver used in place of the system resolver,
+// recording the queries made in the DNS of each trace.
+func WithResolver(r Resolver) Option {
+	return func(c *config) {
+		c.resolver = r
+	}
+}
+
+// WithFamily sets the IP address family used, defaulting to FamilyAny.
+func WithFamily(f Family) Option {
+	return func(c *config) {
+		c.family = f
+	}
+}
+
+// WithLocalAddr sets the local IP address requests are made from.
+func WithLocalAddr(ip net.IP) Option {
+	return func(c *config) {
+		c.localAddr = ip
+	}
+}
+
+// WithInterface binds requests to the network interface name,
+// such as "eth1". This is only supported on Linux.
+func WithInterface(name string) Option {
+	return func(c *config) {
+		c.iface = name
+	}
+}
+
+// WithProxy sets the proxy requests are made through in place of the
+// one given by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables. A nil u disables proxying. HTTP/3 requests are not proxied.
+func WithProxy(u *url.URL) Option {
+	return func(c *config) {
+		c.proxy = http.ProxyURL(u)
+	}
+}
+
+// WithSOCKS5 makes connections through the SOCKS5 proxy at addr, with
+// optional auth, in place of any HTTP proxy. Hosts are resolved by
+// the proxy. HTTP/3 requests are not proxied.
+func WithSOCKS5(addr string, auth *SOCKSAuth) Option {
+	return func(c *config) {
+		c.socks = &socks{addr: addr, auth: auth}
+	}
+}
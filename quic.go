@@ -0,0 +1,205 @@
+package httpstat
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newH3Transport returns an HTTP/3 transport dialing with d.
+func newH3Transport(d *dialer) *h3Transport {
+	config := d.TLSConfig.Clone()
+	if config.ClientSessionCache == nil {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	return &h3Transport{
+		Transport: &http3.Transport{
+			TLSClientConfig: config,
+			QUICConfig: &quic.Config{
+				HandshakeIdleTimeout: d.TLSHandshakeTimeout,
+			},
+			Dial:               d.DialQUIC,
+			DisableCompression: true,
+		},
+	}
+}
+
+// h3Transport is an HTTP/3 transport which closes connections once
+// the response is read, and sends GET and HEAD requests as 0-RTT when
+// the session allows it.
+type h3Transport struct {
+	*http3.Transport
+}
+
+// RoundTrip implementation.
+func (t *h3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req
+
+	if req.Body == nil || req.Body == http.NoBody {
+		switch req.Method {
+		case http.MethodGet:
+			r = early(req, http3.MethodGet0RTT)
+		case http.MethodHead:
+			r = early(req, http3.MethodHead0RTT)
+		}
+	}
+
+	res, err := t.Transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Request = req
+	res.Body = &closeBody{res.Body, t.Transport.CloseIdleConnections}
+
+	return res, nil
+}
+
+// early returns a copy of req using the 0-RTT method.
+func early(req *http.Request, method string) *http.Request {
+	r := *req
+	r.Method = method
+	return &r
+}
+
+// closeBody is a response body which calls fn on close.
+type closeBody struct {
+	io.ReadCloser
+	fn func()
+}
+
+// Close implementation.
+func (b *closeBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.fn()
+	return err
+}
+
+// DialQUIC dials a QUIC connection, reporting DNS to the client trace.
+// QUIC has no handshake apart from TLS, so the handshake is reported
+// as the TLS phase, and the connect phase is left empty.
+func (d *dialer) DialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+	if d.AltSvc != nil {
+		if alt, ok := d.AltSvc.lookup(addr); ok {
+			addr = alt
+		}
+	}
+
+	host, port, err := net.SplitHostPort(d.override(ctx, addr))
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []net.IPAddr{{IP: net.ParseIP(host)}}
+	if ips[0].IP != nil {
+		ips, err = d.filter(host, ips)
+	} else {
+		ips, err = d.lookup(ctx, host)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ip := ips[0]
+
+	trace := httptrace.ContextClientTrace(ctx)
+
+	portnum, err := net.DefaultResolver.LookupPort(ctx, "udp", port)
+	if err != nil {
+		return nil, err
+	}
+
+	laddr := ":0"
+	if a, ok := d.LocalAddr.(*net.TCPAddr); ok {
+		laddr = net.JoinHostPort(a.IP.String(), "0")
+	}
+
+	lc := net.ListenConfig{Control: d.Control}
+	pc, err := lc.ListenPacket(ctx, "udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &quic.Transport{Conn: pc}
+
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+
+	qc, err := tr.DialEarly(ctx, &net.UDPAddr{IP: ip.IP, Port: portnum, Zone: ip.Zone}, tlsConfig, config)
+	if err != nil {
+		tr.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-qc.Context().Done()
+		tr.Close()
+	}()
+
+	if t := traceFromContext(ctx); t != nil {
+		t.quic = newQUICConn(qc)
+	}
+
+	return qc, nil
+}
+
+// quicConn is a QUIC connection which records the
+// completion time of the handshake.
+type quicConn struct {
+	*quic.Conn
+
+	mu        sync.Mutex
+	handshake time.Time
+}
+
+// newQUICConn returns a new quicConn wrapping c.
+func newQUICConn(c *quic.Conn) *quicConn {
+	qc := &quicConn{Conn: c}
+
+	select {
+	case <-c.HandshakeComplete():
+		qc.handshake = time.Now()
+		return qc
+	default:
+	}
+
+	go func() {
+		select {
+		case <-c.HandshakeComplete():
+			qc.mu.Lock()
+			qc.handshake = time.Now()
+			qc.mu.Unlock()
+		case <-c.Context().Done():
+		}
+	}()
+
+	return qc
+}
+
+// handshakeEnd returns the completion time of the handshake, or zero.
+func (c *quicConn) handshakeEnd() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handshake
+}
+
+// resumed returns true if the TLS session was resumed.
+func (c *quicConn) resumed() bool {
+	return c.ConnectionState().TLS.DidResume
+}
+
+// used0RTT returns true if the server accepted 0-RTT data.
+func (c *quicConn) used0RTT() bool {
+	return c.ConnectionState().Used0RTT
+}
@@ -0,0 +1,146 @@
+package httpstat
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedirectPolicy is the policy of the redirects followed.
+type RedirectPolicy struct {
+	// MaxRedirects is the max number of redirects followed,
+	// defaulting to DefaultMaxRedirects when zero.
+	MaxRedirects int
+
+	// SameOrigin only follows redirects to the scheme,
+	// host and port of the request.
+	SameOrigin bool
+
+	// NoDowngrade does not follow redirects from HTTPS to HTTP.
+	NoDowngrade bool
+
+	// NoFollow does not follow redirects, responding with the first
+	// redirect response, whose Location header is the redirect.
+	NoFollow bool
+}
+
+// check implements the CheckRedirect of a client, with a cookie jar when jar is true.
+func (p RedirectPolicy) check(req *http.Request, via []*http.Request, jar bool) error {
+	if p.NoFollow {
+		return http.ErrUseLastResponse
+	}
+
+	if err := checkLoop(req, via, jar); err != nil {
+		return err
+	}
+
+	max := p.MaxRedirects
+	if max == 0 {
+		max = DefaultMaxRedirects
+	}
+
+	if len(via) > max {
+		return ErrMaxRedirectsExceeded
+	}
+
+	first, prev := via[0].URL, via[len(via)-1].URL
+
+	if p.SameOrigin && (req.URL.Scheme != first.Scheme || req.URL.Host != first.Host) {
+		return ErrRedirectCrossOrigin
+	}
+
+	if p.NoDowngrade && prev.Scheme == "https" && req.URL.Scheme == "http" {
+		return ErrRedirectDowngrade
+	}
+
+	return nil
+}
+
+// checkRedirect implements the CheckRedirect of a client. Redirects refused
+// by a configured policy fail with a RedirectError, while the defaults fail
+// with ErrMaxRedirectsExceeded or a RedirectLoopError as they are.
+func (c *config) checkRedirect(req *http.Request, via []*http.Request) error {
+	err := c.redirect.check(req, via, c.jar != nil)
+	if err == nil || err == http.ErrUseLastResponse || c.redirect == (RedirectPolicy{}) {
+		return err
+	}
+
+	return &RedirectError{Err: err}
+}
+
+// checkLoop returns a RedirectLoopError when req repeats a request of via.
+// With a cookie jar, a request is not repeated when a response since set
+// a cookie, such as a login redirecting back to the page requested.
+func checkLoop(req *http.Request, via []*http.Request, jar bool) error {
+	for i, r := range via {
+		if r.Method != req.Method || r.URL.String() != req.URL.String() {
+			continue
+		}
+
+		if jar && (setCookie(via[i+1:]...) || setCookie(req)) {
+			continue
+		}
+
+		var cycle []string
+		for _, r := range via[i:] {
+			cycle = append(cycle, r.URL.String())
+		}
+
+		return &RedirectLoopError{
+			Cycle: append(cycle, req.URL.String()),
+		}
+	}
+
+	return nil
+}
+
+// setCookie returns true if the redirect response
+// of any of the requests set a cookie.
+func setCookie(requests ...*http.Request) bool {
+	for _, r := range requests {
+		if r.Response != nil && len(r.Response.Cookies()) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedirectLoopError is the error of a redirect to a URL already requested.
+type RedirectLoopError struct {
+	// Cycle of URLs, starting and ending with the URL repeated.
+	Cycle []string
+}
+
+// Error implementation.
+func (e *RedirectLoopError) Error() string {
+	return "redirect loop: " + strings.Join(e.Cycle, " -> ")
+}
+
+// Is implementation.
+func (e *RedirectLoopError) Is(target error) bool {
+	return target == ErrRedirectLoop
+}
+
+// RedirectError is the error of a redirect refused by the policy set
+// with WithRedirectPolicy. Match its Err with errors.Is.
+type RedirectError struct {
+	// Err such as ErrMaxRedirectsExceeded.
+	Err error
+
+	// URL of the redirect refused.
+	URL string
+
+	// Traces of the requests made, the last
+	// of which responded with the redirect.
+	Traces []Trace
+}
+
+// Error implementation.
+func (e *RedirectError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap implementation.
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,93 @@
+package httpstat_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// chain redirects /n to /n+1 until /max.
+func chain(max int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Path[1:])
+		if n < max {
+			http.Redirect(w, r, fmt.Sprintf("/%d", n+1), http.StatusFound)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}
+}
+
+func TestResponse_RedirectPolicy(t *testing.T) {
+	t.Run("with max redirects", func(t *testing.T) {
+		s := server(chain(3))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL+"/0", nil, nil, httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{MaxRedirects: 3}))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 3, res.Redirects())
+
+		_, err = httpstat.Request("GET", s.URL+"/0", nil, nil, httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{MaxRedirects: 2}))
+		assert.True(t, errors.Is(err, httpstat.ErrMaxRedirectsExceeded), "max redirects")
+
+		var e *httpstat.RedirectError
+		assert.True(t, errors.As(err, &e), "redirect error")
+		assert.Equal(t, s.URL+"/3", e.URL)
+		assert.Len(t, e.Traces, 3)
+		assert.Equal(t, s.URL+"/2", e.Traces[2].URL())
+		assert.Equal(t, 302, e.Traces[2].Status())
+	})
+
+	t.Run("with no follow", func(t *testing.T) {
+		s := server(chain(3))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL+"/0", nil, nil, httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{NoFollow: true}))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 302, res.Status())
+		assert.Equal(t, "/1", res.Header().Get("Location"))
+		assert.Equal(t, 0, res.Redirects())
+	})
+
+	t.Run("with same origin", func(t *testing.T) {
+		other := server(noRedirects)
+		defer other.Close()
+
+		s := server(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		})
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{SameOrigin: true}))
+		assert.EqualError(t, err, "cross-origin redirect not allowed")
+
+		var e *httpstat.RedirectError
+		assert.True(t, errors.As(err, &e), "redirect error")
+		assert.Equal(t, other.URL, e.URL)
+		assert.Len(t, e.Traces, 1)
+
+		_, err = httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+	})
+
+	t.Run("with no downgrade", func(t *testing.T) {
+		other := server(noRedirects)
+		defer other.Close()
+
+		s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		}))
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithRedirectPolicy(httpstat.RedirectPolicy{NoDowngrade: true}))
+		assert.EqualError(t, err, "redirect from HTTPS to HTTP not allowed")
+		assert.True(t, errors.Is(err, httpstat.ErrRedirectDowngrade), "downgrade")
+	})
+}
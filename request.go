@@ -2,9 +2,13 @@ package httpstat
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"io"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -14,29 +18,104 @@ import (
 var DefaultMaxRedirects = 5
 
 // DefaultClient used for requests.
-var DefaultClient = &http.Client{
-	CheckRedirect: checkRedirect,
-	Timeout:       10 * time.Second,
-	Transport: &http.Transport{
-		DisableCompression: true,
-		Proxy:              http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
+var DefaultClient = NewClient()
+
+// NewClient returns a new client with the given options.
+func NewClient(options ...Option) *http.Client {
+	c := newConfig(options)
+	d := newDialer(c)
+
+	proxy := traceProxy(c.proxy)
+	if d.SOCKS != nil {
+		proxy = nil
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		DisableCompression:    true,
+		Proxy:                 proxy,
+		DialContext:           d.DialContext,
+		DialTLSContext:        d.DialTLSContext,
+		DisableKeepAlives:     true,
+		MaxIdleConns:          10,
+		TLSClientConfig:       d.TLSConfig.Clone(),
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		Protocols:             c.protocol.protocols(),
+	}
+
+	switch {
+	case c.protocol == ProtocolHTTP3:
+		transport = newH3Transport(d)
+	case c.altSvc:
+		d.AltSvc = &altSvcCache{}
+		transport = &altSvcTransport{
+			tcp:   transport,
+			h3:    newH3Transport(d),
+			cache: d.AltSvc,
+		}
+	}
+
+	return &http.Client{
+		CheckRedirect: c.checkRedirect,
+		Jar:           c.jar,
+		Timeout:       c.timeout,
+		Transport:     transport,
+	}
+}
+
+// newDialer returns a dialer with the given config.
+func newDialer(c *config) *dialer {
+	tlsConfig := &tls.Config{}
+	if c.tlsConfig != nil {
+		tlsConfig = c.tlsConfig.Clone()
+	}
+
+	if c.clientCert != nil {
+		tlsConfig.GetClientCertificate = c.clientCert
+	}
+
+	if c.rootCAs != nil {
+		tlsConfig.RootCAs = c.rootCAs
+	}
+
+	if c.serverName != "" {
+		tlsConfig.ServerName = c.serverName
+	}
+
+	if c.insecure {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if c.sessionCache != nil {
+		tlsConfig.ClientSessionCache = c.sessionCache
+	}
+
+	tlsConfig.GetClientCertificate = clientCertificate(tlsConfig)
+
+	d := &dialer{
+		Dialer: net.Dialer{
 			Timeout:   5 * time.Second,
 			KeepAlive: 0,
-		}).DialContext,
-		DisableKeepAlives:   true,
-		MaxIdleConns:        10,
+		},
+		Protocol:            c.protocol,
+		Resolve:             c.resolve,
+		DNS:                 c.resolver,
+		Family:              c.family,
+		SOCKS:               c.socks,
+		Revocation:          c.revocation,
+		TLSConfig:           tlsConfig,
 		TLSHandshakeTimeout: 5 * time.Second,
-	},
-}
+	}
 
-// Check redirect.
-func checkRedirect(req *http.Request, via []*http.Request) error {
-	if len(via) > DefaultMaxRedirects {
-		return ErrMaxRedirectsExceeded
+	if c.localAddr != nil {
+		d.LocalAddr = &net.TCPAddr{IP: c.localAddr}
 	}
 
-	return nil
+	if c.iface != "" {
+		d.Control = bindToDevice(c.iface)
+	}
+
+	return d
 }
 
 // Size writer.
@@ -53,17 +132,72 @@ func (w sizeWriter) Size() int {
 	return int(w)
 }
 
+// hopTransport records the URL and response of each request in its trace.
+type hopTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip implementation.
+func (t *hopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.RoundTripper
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t := traceFromContext(req.Context()); t != nil {
+		t.url = req.URL.String()
+		t.status = res.StatusCode
+		t.header = res.Header
+	}
+
+	return res, nil
+}
+
+// Body reader.
+type bodyReader struct {
+	io.ReadCloser
+	ctx context.Context
+}
+
+// Read implementation.
+func (r *bodyReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if t := traceFromContext(r.ctx); t != nil {
+		t.bodySize += n
+	}
+	return n, err
+}
+
 // Response interface.
 type Response interface {
 	Status() int
+	Proto() string
 	Redirects() int
 	TLS() bool
+	Resumed() bool
+	Used0RTT() bool
+	Revocation() *Revocation
 	Header() http.Header
 	HeaderSize() int
+	HeaderSizeCompressed() int
+	RequestHeaderSize() int
+	RequestHeaderSizeCompressed() int
+	RequestBodySize() int
 	BodySize() int
+	WriteError() error
+	Informational() []Informational
 	TimeDNS() time.Duration
 	TimeConnect() time.Duration
+	TimeSOCKS() time.Duration
 	TimeTLS() time.Duration
+	TimeSend() time.Duration
+	TimeUpload() time.Duration
+	TimeContinue() time.Duration
 	TimeWait() time.Duration
 	TimeResponse(time.Time) time.Duration
 	TimeDownload(time.Time) time.Duration
@@ -76,31 +210,53 @@ type Response interface {
 
 // Stats is an opaque struct which can be useful for JSON marshaling.
 type Stats struct {
-	Status                 int           `json:"status,omitempty"`
-	Redirects              int           `json:"redirects,omitempty"`
-	TLS                    bool          `json:"tls"`
-	Header                 http.Header   `json:"header,omitempty"`
-	HeaderSize             int           `json:"header_size,omitempty"`
-	BodySize               int           `json:"body_size,omitempty"`
-	TimeDNS                time.Duration `json:"time_dns"`
-	TimeConnect            time.Duration `json:"time_connect"`
-	TimeTLS                time.Duration `json:"time_tls"`
-	TimeWait               time.Duration `json:"time_wait"`
-	TimeResponse           time.Duration `json:"time_response"`
-	TimeDownload           time.Duration `json:"time_download"`
-	TimeTotal              time.Duration `json:"time_total"`
-	TimeTotalWithRedirects time.Duration `json:"time_total_with_redirects,omitempty"`
-	TimeRedirects          time.Duration `json:"time_redirects,omitempty"`
-	Traces                 []*Stats      `json:"traces,omitempty"`
+	URL                         string          `json:"url,omitempty"`
+	Status                      int             `json:"status,omitempty"`
+	Proto                       string          `json:"proto,omitempty"`
+	Redirects                   int             `json:"redirects,omitempty"`
+	LocalAddr                   string          `json:"local_addr,omitempty"`
+	Proxy                       *Proxy          `json:"proxy,omitempty"`
+	Override                    *Override       `json:"override,omitempty"`
+	DNS                         *DNS            `json:"dns,omitempty"`
+	TCPInfo                     *TCPInfo        `json:"tcp_info,omitempty"`
+	Revocation                  *Revocation     `json:"revocation,omitempty"`
+	TLS                         bool            `json:"tls"`
+	ClientCertRequested         bool            `json:"client_cert_requested,omitempty"`
+	ClientCertSent              bool            `json:"client_cert_sent,omitempty"`
+	Resumed                     bool            `json:"resumed,omitempty"`
+	Used0RTT                    bool            `json:"used_0rtt,omitempty"`
+	Header                      http.Header     `json:"header,omitempty"`
+	HeaderSize                  int             `json:"header_size,omitempty"`
+	HeaderSizeCompressed        int             `json:"header_size_compressed,omitempty"`
+	RequestHeaderSize           int             `json:"request_header_size,omitempty"`
+	RequestHeaderSizeCompressed int             `json:"request_header_size_compressed,omitempty"`
+	RequestBodySize             int             `json:"request_body_size,omitempty"`
+	BodySize                    int             `json:"body_size,omitempty"`
+	WriteError                  string          `json:"write_error,omitempty"`
+	Informational               []Informational `json:"informational,omitempty"`
+	TimeDNS                     time.Duration   `json:"time_dns"`
+	TimeConnect                 time.Duration   `json:"time_connect"`
+	TimeSOCKS                   time.Duration   `json:"time_socks,omitempty"`
+	TimeTLS                     time.Duration   `json:"time_tls"`
+	TimeSend                    time.Duration   `json:"time_send"`
+	TimeUpload                  time.Duration   `json:"time_upload"`
+	TimeContinue                time.Duration   `json:"time_continue,omitempty"`
+	TimeWait                    time.Duration   `json:"time_wait"`
+	TimeResponse                time.Duration   `json:"time_response"`
+	TimeDownload                time.Duration   `json:"time_download"`
+	TimeTotal                   time.Duration   `json:"time_total"`
+	TimeTotalWithRedirects      time.Duration   `json:"time_total_with_redirects,omitempty"`
+	TimeRedirects               time.Duration   `json:"time_redirects,omitempty"`
+	Traces                      []*Stats        `json:"traces,omitempty"`
 }
 
 // Response struct.
 type response struct {
-	status     int
-	traces     []Trace
-	headerSize int
-	header     http.Header
-	bodySize   sizeWriter
+	status   int
+	proto    string
+	traces   []Trace
+	header   http.Header
+	bodySize sizeWriter
 }
 
 // Stats returns a struct of stats.
@@ -114,22 +270,36 @@ func (r response) Stats() *Stats {
 	}
 
 	return &Stats{
-		Status:                 r.Status(),
-		Redirects:              r.Redirects(),
-		TLS:                    r.TLS(),
-		Header:                 r.Header(),
-		HeaderSize:             r.HeaderSize(),
-		BodySize:               r.BodySize(),
-		TimeDNS:                r.TimeDNS(),
-		TimeConnect:            r.TimeConnect(),
-		TimeTLS:                r.TimeTLS(),
-		TimeWait:               r.TimeWait(),
-		TimeResponse:           r.TimeResponse(now),
-		TimeDownload:           r.TimeDownload(now),
-		TimeTotal:              r.TimeTotal(now),
-		TimeTotalWithRedirects: r.TimeTotalWithRedirects(now),
-		TimeRedirects:          r.TimeRedirects(),
-		Traces:                 traces,
+		Status:                      r.Status(),
+		Proto:                       r.Proto(),
+		Redirects:                   r.Redirects(),
+		TLS:                         r.TLS(),
+		Resumed:                     r.Resumed(),
+		Used0RTT:                    r.Used0RTT(),
+		Revocation:                  r.Revocation(),
+		Header:                      r.Header(),
+		HeaderSize:                  r.HeaderSize(),
+		HeaderSizeCompressed:        r.HeaderSizeCompressed(),
+		RequestHeaderSize:           r.RequestHeaderSize(),
+		RequestHeaderSizeCompressed: r.RequestHeaderSizeCompressed(),
+		RequestBodySize:             r.RequestBodySize(),
+		BodySize:                    r.BodySize(),
+		WriteError:                  errorString(r.WriteError()),
+		Informational:               r.Informational(),
+		TimeDNS:                     r.TimeDNS(),
+		TimeConnect:                 r.TimeConnect(),
+		TimeSOCKS:                   r.TimeSOCKS(),
+		TimeTLS:                     r.TimeTLS(),
+		TimeSend:                    r.TimeSend(),
+		TimeUpload:                  r.TimeUpload(),
+		TimeContinue:                r.TimeContinue(),
+		TimeWait:                    r.TimeWait(),
+		TimeResponse:                r.TimeResponse(now),
+		TimeDownload:                r.TimeDownload(now),
+		TimeTotal:                   r.TimeTotal(now),
+		TimeTotalWithRedirects:      r.TimeTotalWithRedirects(now),
+		TimeRedirects:               r.TimeRedirects(),
+		Traces:                      traces,
 	}
 }
 
@@ -138,6 +308,15 @@ func (r *response) Status() int {
 	return r.status
 }
 
+// Proto implementation.
+func (r *response) Proto() string {
+	if v := r.last().Proto(); v != "" {
+		return v
+	}
+
+	return r.proto
+}
+
 // Last trace.
 func (r *response) last() Trace {
 	return r.traces[len(r.traces)-1]
@@ -148,6 +327,21 @@ func (r *response) TLS() bool {
 	return r.last().TLS()
 }
 
+// Resumed implementation.
+func (r *response) Resumed() bool {
+	return r.last().Resumed()
+}
+
+// Used0RTT implementation.
+func (r *response) Used0RTT() bool {
+	return r.last().Used0RTT()
+}
+
+// Revocation implementation.
+func (r *response) Revocation() *Revocation {
+	return r.last().Revocation()
+}
+
 // Redirects implementation.
 func (r *response) Redirects() int {
 	return len(r.traces) - 1
@@ -158,9 +352,39 @@ func (r *response) BodySize() int {
 	return int(r.bodySize)
 }
 
+// RequestBodySize implementation.
+func (r *response) RequestBodySize() int {
+	return r.last().RequestBodySize()
+}
+
+// WriteError implementation.
+func (r *response) WriteError() error {
+	return r.last().WriteError()
+}
+
+// Informational implementation.
+func (r *response) Informational() []Informational {
+	return r.last().Informational()
+}
+
 // HeaderSize implementation.
 func (r *response) HeaderSize() int {
-	return r.headerSize
+	return r.last().HeaderSize()
+}
+
+// HeaderSizeCompressed implementation.
+func (r *response) HeaderSizeCompressed() int {
+	return r.last().HeaderSizeCompressed()
+}
+
+// RequestHeaderSize implementation.
+func (r *response) RequestHeaderSize() int {
+	return r.last().RequestHeaderSize()
+}
+
+// RequestHeaderSizeCompressed implementation.
+func (r *response) RequestHeaderSizeCompressed() int {
+	return r.last().RequestHeaderSizeCompressed()
 }
 
 // TimeDownload implementation.
@@ -183,11 +407,31 @@ func (r *response) TimeConnect() time.Duration {
 	return r.last().TimeConnect()
 }
 
+// TimeSOCKS implementation.
+func (r *response) TimeSOCKS() time.Duration {
+	return r.last().TimeSOCKS()
+}
+
 // TimeTLS implementation.
 func (r *response) TimeTLS() time.Duration {
 	return r.last().TimeTLS()
 }
 
+// TimeSend implementation.
+func (r *response) TimeSend() time.Duration {
+	return r.last().TimeSend()
+}
+
+// TimeUpload implementation.
+func (r *response) TimeUpload() time.Duration {
+	return r.last().TimeUpload()
+}
+
+// TimeContinue implementation.
+func (r *response) TimeContinue() time.Duration {
+	return r.last().TimeContinue()
+}
+
 // TimeWait implementation.
 func (r *response) TimeWait() time.Duration {
 	return r.last().TimeWait()
@@ -225,9 +469,20 @@ func (r *response) Traces() []Trace {
 	return r.traces
 }
 
-// RequestWithClient performs a traced request.
+// RequestWithClient performs a traced request. Servers listening on
+// a Unix socket are requested with URLs such as "unix:///run/app.sock:/healthz".
 func RequestWithClient(client *http.Client, method, uri string, header http.Header, body io.Reader) (Response, error) {
-	req, err := http.NewRequest(method, uri, body)
+	return request(context.Background(), client, method, uri, header, body, nil)
+}
+
+// request performs a traced request, writing the response body to w when not nil.
+func request(ctx context.Context, client *http.Client, method, uri string, header http.Header, body io.Reader, w io.Writer) (Response, error) {
+	if socket, httpURL, ok := unixURL(uri); ok {
+		ctx = context.WithValue(ctx, unixKey{}, socket)
+		uri = httpURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
 	if err != nil {
 		return nil, err
 	}
@@ -239,29 +494,174 @@ func RequestWithClient(client *http.Client, method, uri string, header http.Head
 	}
 
 	var out response
-	req = req.WithContext(WithTraces(req.Context(), &out.traces))
+	ctx = WithTraces(ctx, &out.traces)
+	req = req.WithContext(ctx)
+
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &bodyReader{req.Body, ctx}
+	}
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil || body == http.NoBody {
+				return body, err
+			}
+			return &bodyReader{body, ctx}, nil
+		}
+	}
+
+	hops := *client
+	hops.Transport = &hopTransport{client.Transport}
+
+	res, err := hops.Do(req)
+
+	var redirectErr *RedirectError
+	if errors.As(err, &redirectErr) {
+		redirectErr.Traces = out.traces
+		if loc, err := res.Location(); err == nil {
+			redirectErr.URL = loc.String()
+		}
+		return nil, redirectErr
+	}
 
-	res, err := client.Do(req)
 	if err != nil {
 		return nil, normalizeError(err)
 	}
 	defer res.Body.Close()
 
 	out.status = res.StatusCode
+	out.proto = res.Proto
 
-	if _, err := io.Copy(&out.bodySize, res.Body); err != nil {
+	var dst io.Writer = &out.bodySize
+	if w != nil {
+		dst = io.MultiWriter(dst, w)
+	}
+
+	if _, err := io.Copy(dst, res.Body); err != nil {
 		return nil, err
 	}
 
-	var resHeader bytes.Buffer
-	res.Header.Write(&resHeader)
+	// capture before the transport closes the connection
+	if t, ok := out.last().(*trace); ok && t.conn != nil {
+		t.conn.captureTCPInfo()
+	}
+
 	out.header = res.Header
-	out.headerSize = resHeader.Len()
 
 	return &out, nil
 }
 
-// Request performs a traced request.
-func Request(method, uri string, header http.Header, body io.Reader) (Response, error) {
-	return RequestWithClient(DefaultClient, method, uri, header, body)
+// Request performs a traced request. When options are given
+// a new client is used in place of the DefaultClient.
+func Request(method, uri string, header http.Header, body io.Reader, options ...Option) (Response, error) {
+	client := DefaultClient
+	if len(options) > 0 {
+		client = NewClient(options...)
+	}
+
+	return RequestWithClient(client, method, uri, header, body)
+}
+
+// DualStack is the result of a request made over IPv4 and IPv6.
+type DualStack struct {
+	// IPv4 response, or nil when IPv4Err is set.
+	IPv4 Response
+
+	// IPv4Err is the error of the IPv4 request.
+	IPv4Err error
+
+	// IPv6 response, or nil when IPv6Err is set.
+	IPv6 Response
+
+	// IPv6Err is the error of the IPv6 request.
+	IPv6Err error
+}
+
+// RequestDualStack performs a traced request over IPv4 and IPv6
+// concurrently, so that the paths can be compared side by side.
+// The error is only set when the body cannot be read.
+func RequestDualStack(method, uri string, header http.Header, body io.Reader, options ...Option) (*DualStack, error) {
+	replay, err := replayable(body)
+	if err != nil {
+		return nil, err
+	}
+
+	request := func(f Family) (Response, error) {
+		options := append(options[:len(options):len(options)], WithFamily(f))
+		return RequestWithClient(NewClient(options...), method, uri, header, replay())
+	}
+
+	var v DualStack
+	var wg sync.WaitGroup
+	wg.Go(func() { v.IPv4, v.IPv4Err = request(FamilyIPv4) })
+	wg.Go(func() { v.IPv6, v.IPv6Err = request(FamilyIPv6) })
+	wg.Wait()
+
+	return &v, nil
+}
+
+// Resumption is the result of two consecutive requests sharing
+// a TLS session cache, the second resuming the session of the first.
+type Resumption struct {
+	// Full response, with a full handshake.
+	Full Response
+
+	// Resumed response, with a resumed handshake when honored.
+	Resumed Response
+
+	// DidResume is true when the server resumed the session.
+	DidResume bool
+
+	// Used0RTT is true when the server accepted 0-RTT data, over HTTP/3.
+	Used0RTT bool
+
+	// Savings is the TimeTLS of the full handshake less the resumed.
+	Savings time.Duration
+}
+
+// RequestResumption performs two consecutive traced requests over new
+// connections sharing a TLS session cache, so that full and resumed
+// handshakes can be compared. Use ProtocolHTTP3 to measure 0-RTT.
+func RequestResumption(method, uri string, header http.Header, body io.Reader, options ...Option) (*Resumption, error) {
+	replay, err := replayable(body)
+	if err != nil {
+		return nil, err
+	}
+
+	options = append(options[:len(options):len(options)], WithSessionCache(tls.NewLRUClientSessionCache(0)))
+	client := NewClient(options...)
+
+	full, err := RequestWithClient(client, method, uri, header, replay())
+	if err != nil {
+		return nil, err
+	}
+
+	resumed, err := RequestWithClient(client, method, uri, header, replay())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resumption{
+		Full:      full,
+		Resumed:   resumed,
+		DidResume: resumed.Resumed(),
+		Used0RTT:  resumed.Used0RTT(),
+		Savings:   full.TimeTLS() - resumed.TimeTLS(),
+	}, nil
+}
+
+// replayable reads body, returning a function which
+// returns a new reader of it, or nil when body is nil.
+func replayable(body io.Reader) (func() io.Reader, error) {
+	if body == nil {
+		return func() io.Reader { return nil }, nil
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() io.Reader { return bytes.NewReader(b) }, nil
 }
@@ -0,0 +1,276 @@
+package httpstat
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Resolver resolves the IP addresses of hosts.
+type Resolver interface {
+	Resolve(ctx context.Context, host string) (*DNS, error)
+}
+
+// DNS is the resolution of a host.
+type DNS struct {
+	// Host resolved.
+	Host string `json:"host"`
+
+	// CNAMEs followed to the canonical name, in order.
+	CNAMEs []string `json:"cnames,omitempty"`
+
+	// Addrs resolved.
+	Addrs []net.IP `json:"addrs,omitempty"`
+
+	// Queries made.
+	Queries []DNSQuery `json:"queries"`
+}
+
+// DNSQuery is a query made to resolve a host.
+type DNSQuery struct {
+	// Type of the query, such as "A" or "AAAA".
+	Type string `json:"type"`
+
+	// Server which answered, such as "udp://127.0.0.1:53".
+	Server string `json:"server,omitempty"`
+
+	// Records answered.
+	Records []DNSRecord `json:"records,omitempty"`
+
+	// Error of the query.
+	Error string `json:"error,omitempty"`
+
+	// Time of the query, including Connect and TLS.
+	Time time.Duration `json:"time"`
+
+	// Connect is the time taken to connect to the
+	// server, when the query established a connection.
+	Connect time.Duration `json:"connect,omitempty"`
+
+	// TLS is the time taken by the TLS handshake with
+	// the server, when the query established a connection.
+	TLS time.Duration `json:"tls,omitempty"`
+}
+
+// DNSRecord is a record answered to a query.
+type DNSRecord struct {
+	Name  string        `json:"name"`
+	Type  string        `json:"type"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+	Value string        `json:"value"`
+}
+
+// NewResolver returns a resolver querying the nameserver at addr over
+// network "udp" or "tcp". Truncated UDP responses are retried over TCP.
+func NewResolver(network, addr string) Resolver {
+	return &nameserver{
+		network: network,
+		addr:    addr,
+	}
+}
+
+// NewNetResolver returns a resolver using r. Only the addresses and
+// timing of each query are known, not the server, TTLs or CNAMEs.
+func NewNetResolver(r *net.Resolver) Resolver {
+	return &netResolver{r}
+}
+
+// exchanger exchanges DNS messages with a server,
+// recording the server and connection timing in q.
+type exchanger interface {
+	exchange(ctx context.Context, m *dns.Msg, q *DNSQuery) (*dns.Msg, error)
+}
+
+// nameserver is a DNS server.
+type nameserver struct {
+	network string
+	addr    string
+}
+
+// Resolve implementation.
+func (n *nameserver) Resolve(ctx context.Context, host string) (*DNS, error) {
+	return resolve(ctx, n, host)
+}
+
+// exchange implementation.
+func (n *nameserver) exchange(ctx context.Context, m *dns.Msg, q *DNSQuery) (*dns.Msg, error) {
+	c := &dns.Client{Net: n.network}
+
+	r, _, err := c.ExchangeContext(ctx, m, n.addr)
+	if err == nil && r.Truncated && c.Net == "udp" {
+		c.Net = "tcp"
+		r, _, err = c.ExchangeContext(ctx, m, n.addr)
+	}
+
+	q.Server = c.Net + "://" + n.addr
+
+	return r, err
+}
+
+// resolve the A and AAAA records of host concurrently.
+func resolve(ctx context.Context, e exchanger, host string) (*DNS, error) {
+	types := []uint16{dns.TypeA, dns.TypeAAAA}
+	queries := make([]DNSQuery, len(types))
+	answers := make([][]dns.RR, len(types))
+
+	var wg sync.WaitGroup
+	for i, typ := range types {
+		wg.Go(func() {
+			queries[i], answers[i] = query(ctx, e, host, typ)
+		})
+	}
+	wg.Wait()
+
+	v := &DNS{
+		Host:    host,
+		Queries: queries,
+	}
+
+	for i, rrs := range answers {
+		for _, rr := range rrs {
+			switch rr := rr.(type) {
+			case *dns.A:
+				v.Addrs = append(v.Addrs, rr.A)
+			case *dns.AAAA:
+				v.Addrs = append(v.Addrs, rr.AAAA)
+			case *dns.CNAME:
+				// both queries follow the same chain
+				if i == 0 || len(answers[0]) == 0 {
+					v.CNAMEs = append(v.CNAMEs, strings.TrimSuffix(rr.Target, "."))
+				}
+			}
+		}
+	}
+
+	if len(v.Addrs) == 0 {
+		return v, resolveError(v)
+	}
+
+	return v, nil
+}
+
+// resolveError returns the error of a resolution without addresses.
+func resolveError(v *DNS) error {
+	err := &net.DNSError{
+		Err:        "no such host",
+		Name:       v.Host,
+		IsNotFound: true,
+	}
+
+	for _, q := range v.Queries {
+		if q.Error != "" {
+			err.Err = q.Error
+			err.IsNotFound = false
+			break
+		}
+	}
+
+	return err
+}
+
+// query host for records of type typ.
+func query(ctx context.Context, e exchanger, host string, typ uint16) (DNSQuery, []dns.RR) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(host), typ)
+
+	q := DNSQuery{
+		Type: dns.TypeToString[typ],
+	}
+
+	start := time.Now()
+	r, err := e.exchange(ctx, m, &q)
+	q.Time = time.Since(start)
+
+	if err != nil {
+		q.Error = err.Error()
+		return q, nil
+	}
+
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		q.Error = strings.ToLower(dns.RcodeToString[r.Rcode])
+	}
+
+	for _, rr := range r.Answer {
+		h := rr.Header()
+
+		record := DNSRecord{
+			Name: strings.TrimSuffix(h.Name, "."),
+			Type: dns.TypeToString[h.Rrtype],
+			TTL:  time.Duration(h.Ttl) * time.Second,
+		}
+
+		switch rr := rr.(type) {
+		case *dns.A:
+			record.Value = rr.A.String()
+		case *dns.AAAA:
+			record.Value = rr.AAAA.String()
+		case *dns.CNAME:
+			record.Value = strings.TrimSuffix(rr.Target, ".")
+		default:
+			record.Value = strings.TrimPrefix(rr.String(), h.String())
+		}
+
+		q.Records = append(q.Records, record)
+	}
+
+	return q, r.Answer
+}
+
+// netResolver is a net.Resolver.
+type netResolver struct {
+	*net.Resolver
+}
+
+// Resolve implementation.
+func (r *netResolver) Resolve(ctx context.Context, host string) (*DNS, error) {
+	networks := []string{"ip4", "ip6"}
+	types := []string{"A", "AAAA"}
+	queries := make([]DNSQuery, len(networks))
+	addrs := make([][]net.IP, len(networks))
+
+	var wg sync.WaitGroup
+	for i, network := range networks {
+		wg.Go(func() {
+			start := time.Now()
+			ips, err := r.LookupIP(ctx, network, host)
+
+			q := DNSQuery{
+				Type: types[i],
+				Time: time.Since(start),
+			}
+
+			if e, ok := err.(*net.DNSError); ok && !e.IsNotFound {
+				q.Error = e.Err
+			} else if !ok && err != nil {
+				q.Error = err.Error()
+			}
+
+			for _, ip := range ips {
+				q.Records = append(q.Records, DNSRecord{
+					Name:  host,
+					Type:  types[i],
+					Value: ip.String(),
+				})
+			}
+
+			queries[i], addrs[i] = q, ips
+		})
+	}
+	wg.Wait()
+
+	v := &DNS{
+		Host:    host,
+		Addrs:   append(addrs[0], addrs[1]...),
+		Queries: queries,
+	}
+
+	if len(v.Addrs) == 0 {
+		return v, resolveError(v)
+	}
+
+	return v, nil
+}
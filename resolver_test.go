@@ -0,0 +1,255 @@
+package httpstat_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// dnsHandler answers www.example.test as a CNAME of example.test on
+// the IPv4 loopback address, and dual.example.test on both loopbacks.
+var dnsHandler = dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+
+	q := req.Question[0]
+	switch q.Name {
+	case "www.example.test.":
+		m.Answer = append(m.Answer, &dns.CNAME{
+			Hdr:    dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300},
+			Target: "example.test.",
+		})
+		if q.Qtype == dns.TypeA {
+			time.Sleep(10 * time.Millisecond)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: "example.test.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.IPv4(127, 0, 0, 1),
+			})
+		}
+	case "dual.example.test.":
+		switch q.Qtype {
+		case dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.IPv4(127, 0, 0, 1),
+			})
+		case dns.TypeAAAA:
+			m.Answer = append(m.Answer, &dns.AAAA{
+				Hdr:  dns.RR_Header{Name: q.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 60},
+				AAAA: net.IPv6loopback,
+			})
+		}
+	default:
+		m.Rcode = dns.RcodeNameError
+	}
+
+	w.WriteMsg(m)
+})
+
+// dnsServer serves dnsHandler over network "udp", "tcp"
+// or "tcp-tls", returning the address of the server.
+func dnsServer(t testing.TB, network string, config *tls.Config) string {
+	s := &dns.Server{Net: network, Handler: dnsHandler}
+
+	switch network {
+	case "udp":
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err, "listen")
+		s.PacketConn = pc
+	default:
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err, "listen")
+		if config != nil {
+			l = tls.NewListener(l, config)
+		}
+		s.Listener = l
+	}
+
+	started := make(chan struct{})
+	s.NotifyStartedFunc = func() { close(started) }
+	go s.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { s.Shutdown() })
+
+	if s.PacketConn != nil {
+		return s.PacketConn.LocalAddr().String()
+	}
+
+	return s.Listener.Addr().String()
+}
+
+// dohWriter is a dns.ResponseWriter for DoH requests.
+type dohWriter struct {
+	dns.ResponseWriter
+	w http.ResponseWriter
+}
+
+// WriteMsg implementation.
+func (w *dohWriter) WriteMsg(m *dns.Msg) error {
+	b, err := m.Pack()
+	if err != nil {
+		return err
+	}
+
+	w.w.Header().Set("Content-Type", "application/dns-message")
+	_, err = w.w.Write(b)
+	return err
+}
+
+// dohServer serves dnsHandler over HTTPS.
+func dohServer(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+
+	m := new(dns.Msg)
+	if err := m.Unpack(b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dnsHandler.ServeDNS(&dohWriter{w: w}, m)
+}
+
+func TestResponse_DNS(t *testing.T) {
+	s := server(noRedirects)
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	uri := "http://" + net.JoinHostPort("www.example.test", port)
+
+	for _, network := range []string{"udp", "tcp"} {
+		t.Run("with "+network, func(t *testing.T) {
+			addr := dnsServer(t, network, nil)
+
+			res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithResolver(httpstat.NewResolver(network, addr)))
+			assert.NoError(t, err, "request")
+			assert.Equal(t, 200, res.Status(), "status")
+
+			v := res.Traces()[0].DNS()
+			assert.Equal(t, "www.example.test", v.Host)
+			assert.Equal(t, []string{"example.test"}, v.CNAMEs)
+			assert.Equal(t, "127.0.0.1", v.Addrs[0].String())
+			assert.Len(t, v.Queries, 2)
+
+			a := v.Queries[0]
+			assert.Equal(t, "A", a.Type)
+			assert.Equal(t, network+"://"+addr, a.Server)
+			assert.Equal(t, []httpstat.DNSRecord{
+				{Name: "www.example.test", Type: "CNAME", TTL: 300 * time.Second, Value: "example.test"},
+				{Name: "example.test", Type: "A", TTL: 60 * time.Second, Value: "127.0.0.1"},
+			}, a.Records)
+			assert.True(t, a.Time >= 10*time.Millisecond, "query time")
+
+			aaaa := v.Queries[1]
+			assert.Equal(t, "AAAA", aaaa.Type)
+			assert.Len(t, aaaa.Records, 1)
+
+			assert.True(t, res.TimeDNS() >= a.Time, "time")
+			assert.NotNil(t, res.Stats().Traces[0].DNS)
+		})
+	}
+
+	t.Run("with net.Resolver", func(t *testing.T) {
+		addr := dnsServer(t, "udp", nil)
+
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithResolver(httpstat.NewNetResolver(r)))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+
+		v := res.Traces()[0].DNS()
+		assert.Equal(t, "127.0.0.1", v.Addrs[0].String())
+		assert.Equal(t, "A", v.Queries[0].Type)
+		assert.Equal(t, []httpstat.DNSRecord{
+			{Name: "www.example.test", Type: "A", Value: "127.0.0.1"},
+		}, v.Queries[0].Records)
+	})
+
+	t.Run("with an unknown host", func(t *testing.T) {
+		addr := dnsServer(t, "udp", nil)
+
+		_, err := httpstat.Request("GET", "http://example.invalid", nil, nil, httpstat.WithResolver(httpstat.NewResolver("udp", addr)))
+		assert.EqualError(t, err, "no such host")
+	})
+
+	t.Run("without a resolver", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		assert.Nil(t, res.Traces()[0].DNS())
+	})
+}
+
+func TestResponse_DNS_encrypted(t *testing.T) {
+	s := server(noRedirects)
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	uri := "http://" + net.JoinHostPort("www.example.test", port)
+
+	doh := httptest.NewTLSServer(http.HandlerFunc(dohServer))
+	defer doh.Close()
+
+	resolvers := map[string]func(t *testing.T) (httpstat.Resolver, string){
+		"DoT": func(t *testing.T) (httpstat.Resolver, string) {
+			addr := dnsServer(t, "tcp-tls", doh.TLS)
+			return httpstat.NewDoTResolver(addr, rootCAs(doh)), "tls://" + addr
+		},
+
+		"DoH": func(t *testing.T) (httpstat.Resolver, string) {
+			return httpstat.NewDoHResolver(doh.URL+"/dns-query", rootCAs(doh)), doh.URL + "/dns-query"
+		},
+	}
+
+	for name, fn := range resolvers {
+		t.Run("with "+name, func(t *testing.T) {
+			r, server := fn(t)
+			c := httpstat.NewClient(httpstat.WithResolver(r))
+
+			// handshakes reports the number of queries which connected
+			handshakes := func(v *httpstat.DNS) (n int) {
+				for _, q := range v.Queries {
+					assert.Equal(t, server, q.Server)
+					if q.TLS > 0 {
+						assert.NotZero(t, q.Connect, "connect")
+						assert.True(t, q.Time > q.Connect+q.TLS, "time")
+						n++
+					}
+				}
+				return
+			}
+
+			res, err := httpstat.RequestWithClient(c, "GET", uri, nil, nil)
+			assert.NoError(t, err, "request")
+			assert.Equal(t, 200, res.Status(), "status")
+
+			v := res.Traces()[0].DNS()
+			assert.Equal(t, "127.0.0.1", v.Addrs[0].String())
+			assert.Equal(t, []string{"example.test"}, v.CNAMEs)
+			assert.Equal(t, 1, handshakes(v), "handshakes")
+
+			res, err = httpstat.RequestWithClient(c, "GET", uri, nil, nil)
+			assert.NoError(t, err, "request")
+
+			v = res.Traces()[0].DNS()
+			assert.Equal(t, "127.0.0.1", v.Addrs[0].String())
+			assert.Equal(t, 0, handshakes(v), "handshakes")
+		})
+	}
+}
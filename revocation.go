@@ -0,0 +1,294 @@
+package httpstat
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// RevocationMode is a certificate revocation checking mode.
+type RevocationMode int
+
+// Revocation modes available.
+const (
+	// RevocationOff does not check revocation.
+	RevocationOff RevocationMode = iota
+
+	// RevocationStapled checks the OCSP response stapled by the server.
+	RevocationStapled
+
+	// RevocationOnline also queries the OCSP responder and
+	// CRL distribution points of the certificate.
+	RevocationOnline
+)
+
+// Revocation statuses.
+const (
+	RevocationGood    = "good"
+	RevocationRevoked = "revoked"
+	RevocationUnknown = "unknown"
+)
+
+// Revocation sources.
+const (
+	SourceStapled = "stapled"
+	SourceOCSP    = "ocsp"
+	SourceCRL     = "crl"
+)
+
+// Revocation is the revocation status of a server certificate.
+type Revocation struct {
+	// Status is RevocationRevoked when any check reports the certificate
+	// revoked, RevocationGood when any reports it good, or RevocationUnknown.
+	Status string `json:"status"`
+
+	// Checks made.
+	Checks []RevocationCheck `json:"checks,omitempty"`
+}
+
+// RevocationCheck is the revocation status reported by a source.
+type RevocationCheck struct {
+	// Source of the status, such as SourceStapled.
+	Source string `json:"source"`
+
+	// URL of the OCSP responder or CRL, unless stapled.
+	URL string `json:"url,omitempty"`
+
+	// Status of the certificate, such as RevocationGood.
+	Status string `json:"status"`
+
+	// RevokedAt is the time the certificate was revoked.
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+
+	// ThisUpdate is the time the status was produced.
+	ThisUpdate time.Time `json:"this_update,omitzero"`
+
+	// NextUpdate is the time newer status will be available.
+	NextUpdate time.Time `json:"next_update,omitzero"`
+
+	// Fresh is true when the status is current, between
+	// ThisUpdate and NextUpdate.
+	Fresh bool `json:"fresh"`
+
+	// Time taken to fetch the status, unless stapled.
+	Time time.Duration `json:"time,omitempty"`
+
+	// Error of the check, leaving the status unknown.
+	Error string `json:"error,omitempty"`
+}
+
+// checkRevocation checks the revocation of the certificate of cs in the
+// background, returning a function which waits for the result.
+func checkRevocation(cs tls.ConnectionState, mode RevocationMode) func() *Revocation {
+	var v *Revocation
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		v = revocation(cs, mode)
+	}()
+
+	return func() *Revocation {
+		<-done
+		return v
+	}
+}
+
+// revocation checks the revocation of the certificate of cs.
+func revocation(cs tls.ConnectionState, mode RevocationMode) *Revocation {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := cs.PeerCertificates[0]
+	issuer := issuerOf(cs)
+
+	v := &Revocation{
+		Status: RevocationUnknown,
+	}
+
+	if len(cs.OCSPResponse) > 0 {
+		c := RevocationCheck{Source: SourceStapled}
+		c.ocsp(cs.OCSPResponse, cert, issuer)
+		v.Checks = append(v.Checks, c)
+	}
+
+	if mode == RevocationOnline {
+		if len(cert.OCSPServer) > 0 {
+			v.Checks = append(v.Checks, queryOCSP(cert.OCSPServer[0], cert, issuer))
+		}
+
+		for _, url := range cert.CRLDistributionPoints {
+			v.Checks = append(v.Checks, queryCRL(url, cert, issuer))
+		}
+	}
+
+	for _, c := range v.Checks {
+		switch c.Status {
+		case RevocationRevoked:
+			v.Status = RevocationRevoked
+			return v
+		case RevocationGood:
+			v.Status = RevocationGood
+		}
+	}
+
+	return v
+}
+
+// issuerOf returns the issuer of the certificate of cs, or nil.
+func issuerOf(cs tls.ConnectionState) *x509.Certificate {
+	if len(cs.VerifiedChains) > 0 && len(cs.VerifiedChains[0]) > 1 {
+		return cs.VerifiedChains[0][1]
+	}
+
+	if len(cs.PeerCertificates) > 1 {
+		return cs.PeerCertificates[1]
+	}
+
+	return nil
+}
+
+// revocationClient is the client of revocation queries.
+var revocationClient = &http.Client{
+	Timeout: 5 * time.Second,
+}
+
+// queryOCSP queries the OCSP responder at url.
+func queryOCSP(url string, cert, issuer *x509.Certificate) RevocationCheck {
+	c := RevocationCheck{
+		Source: SourceOCSP,
+		URL:    url,
+		Status: RevocationUnknown,
+	}
+
+	if issuer == nil {
+		c.Error = "issuer unknown"
+		return c
+	}
+
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	start := time.Now()
+	b, err := fetch(url, "application/ocsp-request", req)
+	c.Time = time.Since(start)
+
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	c.ocsp(b, cert, issuer)
+	return c
+}
+
+// queryCRL fetches the CRL at url.
+func queryCRL(url string, cert, issuer *x509.Certificate) RevocationCheck {
+	c := RevocationCheck{
+		Source: SourceCRL,
+		URL:    url,
+		Status: RevocationUnknown,
+	}
+
+	if issuer == nil {
+		c.Error = "issuer unknown"
+		return c
+	}
+
+	start := time.Now()
+	b, err := fetch(url, "", nil)
+	c.Time = time.Since(start)
+
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	crl, err := x509.ParseRevocationList(b)
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		c.Error = err.Error()
+		return c
+	}
+
+	c.Status = RevocationGood
+	c.ThisUpdate = crl.ThisUpdate
+	c.NextUpdate = crl.NextUpdate
+	c.Fresh = fresh(crl.ThisUpdate, crl.NextUpdate)
+
+	for _, e := range crl.RevokedCertificateEntries {
+		if e.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			c.Status = RevocationRevoked
+			c.RevokedAt = e.RevocationTime
+			break
+		}
+	}
+
+	return c
+}
+
+// ocsp sets the status of the OCSP response b.
+func (c *RevocationCheck) ocsp(b []byte, cert, issuer *x509.Certificate) {
+	c.Status = RevocationUnknown
+
+	res, err := ocsp.ParseResponseForCert(b, cert, issuer)
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+
+	switch res.Status {
+	case ocsp.Good:
+		c.Status = RevocationGood
+	case ocsp.Revoked:
+		c.Status = RevocationRevoked
+		c.RevokedAt = res.RevokedAt
+	}
+
+	c.ThisUpdate = res.ThisUpdate
+	c.NextUpdate = res.NextUpdate
+	c.Fresh = fresh(res.ThisUpdate, res.NextUpdate)
+}
+
+// fetch url, posting body of the given type when not nil.
+func fetch(url, contentType string, body []byte) ([]byte, error) {
+	var res *http.Response
+	var err error
+
+	if body != nil {
+		res, err = revocationClient.Post(url, contentType, bytes.NewReader(body))
+	} else {
+		res, err = revocationClient.Get(url)
+	}
+
+	if err != nil {
+		return nil, normalizeError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server responded with %s", res.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, 10<<20))
+}
+
+// fresh returns true if now is between thisUpdate and nextUpdate.
+func fresh(thisUpdate, nextUpdate time.Time) bool {
+	now := time.Now()
+	return !now.Before(thisUpdate) && (nextUpdate.IsZero() || now.Before(nextUpdate))
+}
@@ -0,0 +1,267 @@
+package httpstat_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/apex/httpstat"
+)
+
+// pki is a certificate authority with OCSP
+// and CRL responders for a server certificate.
+type pki struct {
+	ca      *x509.Certificate
+	caKey   crypto.Signer
+	cert    tls.Certificate
+	leaf    *x509.Certificate
+	revoked bool
+}
+
+// newPKI returns a pki whose server certificate
+// is revoked when revoked is true.
+func newPKI(t testing.TB, revoked bool) *pki {
+	p := &pki{revoked: revoked}
+
+	var err error
+	p.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "key")
+
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "httpstat CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, ca, ca, p.caKey.Public(), p.caKey)
+	assert.NoError(t, err, "certificate")
+	p.ca, _ = x509.ParseCertificate(der)
+
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/crl" {
+			w.Write(p.crl(t))
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		if _, err := ocsp.ParseRequest(b); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		w.Write(p.ocsp(t))
+	}))
+	t.Cleanup(responder.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "key")
+
+	leaf := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:            []string{responder.URL + "/ocsp"},
+		CRLDistributionPoints: []string{responder.URL + "/crl"},
+	}
+
+	der, err = x509.CreateCertificate(rand.Reader, leaf, p.ca, key.Public(), p.caKey)
+	assert.NoError(t, err, "certificate")
+	p.leaf, _ = x509.ParseCertificate(der)
+
+	p.cert = tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        p.leaf,
+	}
+
+	return p
+}
+
+// ocsp returns an OCSP response for the server certificate.
+func (p *pki) ocsp(t testing.TB) []byte {
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: p.leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+
+	if p.revoked {
+		template.Status = ocsp.Revoked
+		template.RevokedAt = time.Now().Add(-time.Minute).Truncate(time.Second)
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+
+	b, err := ocsp.CreateResponse(p.ca, p.ca, template, p.caKey)
+	assert.NoError(t, err, "ocsp")
+	return b
+}
+
+// crl returns a CRL of the authority.
+func (p *pki) crl(t testing.TB) []byte {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+
+	if p.revoked {
+		template.RevokedCertificateEntries = []x509.RevocationListEntry{{
+			SerialNumber:   p.leaf.SerialNumber,
+			RevocationTime: time.Now().Add(-time.Minute),
+		}}
+	}
+
+	b, err := x509.CreateRevocationList(rand.Reader, template, p.ca, p.caKey)
+	assert.NoError(t, err, "crl")
+	return b
+}
+
+// server returns a TLS server with the server certificate,
+// stapling an OCSP response when staple is true.
+func (p *pki) server(t testing.TB, staple bool) *httptest.Server {
+	cert := p.cert
+	if staple {
+		cert.OCSPStaple = p.ocsp(t)
+	}
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(noRedirects))
+	s.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// roots returns the root CAs of the authority.
+func (p *pki) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.ca)
+	return pool
+}
+
+func TestResponse_Revocation(t *testing.T) {
+	t.Run("with a stapled response", func(t *testing.T) {
+		p := newPKI(t, false)
+		s := p.server(t, true)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(p.roots()), httpstat.WithRevocation(httpstat.RevocationStapled))
+		assert.NoError(t, err, "request")
+
+		v := res.Traces()[0].Revocation()
+		assert.Equal(t, httpstat.RevocationGood, v.Status)
+		assert.Len(t, v.Checks, 1)
+
+		c := v.Checks[0]
+		assert.Equal(t, httpstat.SourceStapled, c.Source)
+		assert.Equal(t, httpstat.RevocationGood, c.Status)
+		assert.True(t, c.Fresh, "fresh")
+		assert.False(t, c.NextUpdate.IsZero(), "next update")
+		assert.Empty(t, c.Error, "error")
+		assert.Equal(t, v, res.Stats().Traces[0].Revocation)
+		assert.Equal(t, v, res.Revocation())
+		assert.Equal(t, v, res.Stats().Revocation)
+	})
+
+	t.Run("with a revoked certificate", func(t *testing.T) {
+		p := newPKI(t, true)
+		s := p.server(t, true)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(p.roots()), httpstat.WithRevocation(httpstat.RevocationOnline))
+		assert.NoError(t, err, "request")
+
+		v := res.Traces()[0].Revocation()
+		assert.Equal(t, httpstat.RevocationRevoked, v.Status)
+		assert.Len(t, v.Checks, 3)
+
+		for _, c := range v.Checks {
+			assert.Equal(t, httpstat.RevocationRevoked, c.Status, c.Source)
+			assert.False(t, c.RevokedAt.IsZero(), "revoked at")
+		}
+	})
+
+	t.Run("with online checks", func(t *testing.T) {
+		p := newPKI(t, false)
+		s := p.server(t, false)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(p.roots()), httpstat.WithRevocation(httpstat.RevocationOnline))
+		assert.NoError(t, err, "request")
+
+		v := res.Traces()[0].Revocation()
+		assert.Equal(t, httpstat.RevocationGood, v.Status)
+		assert.Len(t, v.Checks, 2)
+
+		o := v.Checks[0]
+		assert.Equal(t, httpstat.SourceOCSP, o.Source)
+		assert.Equal(t, p.leaf.OCSPServer[0], o.URL)
+		assert.Equal(t, httpstat.RevocationGood, o.Status)
+		assert.True(t, o.Time >= 10*time.Millisecond, "time")
+		assert.True(t, o.Fresh, "fresh")
+
+		c := v.Checks[1]
+		assert.Equal(t, httpstat.SourceCRL, c.Source)
+		assert.Equal(t, p.leaf.CRLDistributionPoints[0], c.URL)
+		assert.Equal(t, httpstat.RevocationGood, c.Status)
+		assert.NotZero(t, c.Time, "time")
+	})
+
+	t.Run("with an unknown issuer", func(t *testing.T) {
+		p := newPKI(t, false)
+		s := p.server(t, false)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithInsecure(true), httpstat.WithRevocation(httpstat.RevocationOnline))
+		assert.NoError(t, err, "request")
+
+		v := res.Stats().Revocation
+		assert.Equal(t, httpstat.RevocationUnknown, v.Status)
+		assert.Len(t, v.Checks, 2)
+
+		for _, c := range v.Checks {
+			assert.Equal(t, httpstat.RevocationUnknown, c.Status, c.Source)
+			assert.Equal(t, "issuer unknown", c.Error, c.Source)
+		}
+	})
+
+	t.Run("without a stapled response", func(t *testing.T) {
+		p := newPKI(t, false)
+		s := p.server(t, false)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(p.roots()), httpstat.WithRevocation(httpstat.RevocationStapled))
+		assert.NoError(t, err, "request")
+
+		v := res.Traces()[0].Revocation()
+		assert.Equal(t, httpstat.RevocationUnknown, v.Status)
+		assert.Empty(t, v.Checks, "checks")
+	})
+
+	t.Run("when off", func(t *testing.T) {
+		p := newPKI(t, false)
+		s := p.server(t, true)
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(p.roots()))
+		assert.NoError(t, err, "request")
+		assert.Nil(t, res.Traces()[0].Revocation())
+	})
+}
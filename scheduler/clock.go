@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// Clock is the source of time of a scheduler.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel receiving the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the system clock.
+type realClock struct{}
+
+// Now implementation.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implementation.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apex/httpstat"
+)
+
+// Option function.
+type Option func(*Scheduler)
+
+// WithSink adds a sink receiving the result of each run.
+func WithSink(s Sink) Option {
+	return func(v *Scheduler) {
+		v.sinks = append(v.sinks, s)
+	}
+}
+
+// WithConcurrency sets the max number of checks run
+// at once, across all checks, defaulting to 10.
+func WithConcurrency(n int) Option {
+	return func(v *Scheduler) {
+		v.concurrency = n
+	}
+}
+
+// WithInterval sets the interval of checks without one, defaulting
+// to a minute, which is also used when d is not positive.
+func WithInterval(d time.Duration) Option {
+	return func(v *Scheduler) {
+		v.interval = d
+	}
+}
+
+// WithJitter sets the random delay added to each interval, as a fraction
+// of it, defaulting to 0.1, with a negative fraction disabling it. The first
+// run of each check is delayed by up to the jitter of its interval, so that
+// checks are spread out.
+func WithJitter(fraction float64) Option {
+	return func(v *Scheduler) {
+		v.jitter = fraction
+	}
+}
+
+// WithMaxBackoff sets the max interval of a failing check, whose interval
+// doubles with each consecutive failure, defaulting to an hour.
+func WithMaxBackoff(d time.Duration) Option {
+	return func(v *Scheduler) {
+		v.maxBackoff = d
+	}
+}
+
+// WithClock sets the clock, defaulting to the system clock.
+func WithClock(c Clock) Option {
+	return func(v *Scheduler) {
+		v.clock = c
+	}
+}
+
+// WithRand sets the source of the random jitter,
+// returning a number in [0, 1), defaulting to rand.Float64.
+func WithRand(fn func() float64) Option {
+	return func(v *Scheduler) {
+		v.rand = fn
+	}
+}
+
+// WithCheckOptions sets the options of the requests of checks.
+func WithCheckOptions(options ...httpstat.Option) Option {
+	return func(v *Scheduler) {
+		v.checkOptions = options
+	}
+}
@@ -0,0 +1,248 @@
+// Package scheduler runs httpstat checks at intervals, delivering
+// the result of each run to sinks.
+package scheduler
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/apex/httpstat"
+)
+
+// Result is the result of a run of a check.
+type Result struct {
+	// Check run.
+	Check *httpstat.Check
+
+	// Time the run started.
+	Time time.Time
+
+	// Stats of the response, or nil when there is none.
+	Stats *httpstat.Stats
+
+	// Err of the run, such as an *httpstat.AssertionError.
+	Err error
+
+	// Failures is the number of consecutive failed runs, including this one.
+	Failures int
+}
+
+// Sink receives results.
+type Sink interface {
+	Send(Result)
+}
+
+// SinkFunc is a function implementing Sink.
+type SinkFunc func(Result)
+
+// Send implementation.
+func (f SinkFunc) Send(r Result) {
+	f(r)
+}
+
+// Scheduler runs checks at intervals.
+type Scheduler struct {
+	checks       []*httpstat.Check
+	sinks        []Sink
+	concurrency  int
+	interval     time.Duration
+	jitter       float64
+	maxBackoff   time.Duration
+	clock        Clock
+	rand         func() float64
+	checkOptions []httpstat.Option
+
+	sem    chan struct{}
+	sendMu sync.Mutex
+
+	mu   sync.Mutex
+	held map[string]time.Time
+}
+
+// New returns a scheduler of checks with the given options.
+func New(checks []*httpstat.Check, options ...Option) *Scheduler {
+	s := &Scheduler{
+		checks:      checks,
+		concurrency: 10,
+		interval:    time.Minute,
+		jitter:      0.1,
+		maxBackoff:  time.Hour,
+		clock:       realClock{},
+		rand:        rand.Float64,
+		held:        make(map[string]time.Time),
+	}
+
+	for _, o := range options {
+		o(s)
+	}
+
+	if s.interval <= 0 {
+		s.interval = time.Minute
+	}
+
+	s.jitter = max(s.jitter, 0)
+
+	s.sem = make(chan struct{}, max(s.concurrency, 1))
+	return s
+}
+
+// Run runs the checks until ctx is done, then waits for the runs in
+// progress to complete and their results to be sent. Each check is run
+// after its interval has elapsed since its previous run completed, or
+// its backoff when failing. A run failing without a response, such as
+// when the host of the URL of the check is unreachable, also holds off
+// the other checks of the host until its backoff has elapsed, or the host
+// responds to one of them. Sinks are sent one result at a time.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, c := range s.checks {
+		wg.Go(func() { s.loop(ctx, c) })
+	}
+	wg.Wait()
+}
+
+// loop runs check c until ctx is done.
+func (s *Scheduler) loop(ctx context.Context, c *httpstat.Check) {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = s.interval
+	}
+
+	target := targetOf(c)
+	failures := 0
+	delay := s.jitterOf(interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(delay):
+		}
+
+		if d := s.heldFor(target); d > 0 {
+			delay = d
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case s.sem <- struct{}{}:
+		}
+
+		if ctx.Err() != nil {
+			<-s.sem
+			return
+		}
+
+		r := s.run(ctx, c)
+
+		if r.Err != nil {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		r.Failures = failures
+		s.send(r)
+
+		delay = s.backoff(interval, failures)
+		delay += s.jitterOf(delay)
+
+		switch {
+		case r.Stats != nil:
+			s.release(target)
+		case r.Err != nil:
+			s.hold(target, s.clock.Now().Add(delay))
+		}
+	}
+}
+
+// run runs check c, releasing its slot. The run is not cancelled
+// with ctx, so that it completes on shutdown.
+func (s *Scheduler) run(ctx context.Context, c *httpstat.Check) Result {
+	defer func() { <-s.sem }()
+
+	r := Result{
+		Check: c,
+		Time:  s.clock.Now(),
+	}
+
+	res, err := c.Run(context.WithoutCancel(ctx), s.checkOptions...)
+	if res != nil {
+		r.Stats = res.Stats()
+	}
+	r.Err = err
+
+	return r
+}
+
+// send r to the sinks.
+func (s *Scheduler) send(r Result) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for _, sink := range s.sinks {
+		sink.Send(r)
+	}
+}
+
+// hold off the checks of target until t.
+func (s *Scheduler) hold(target string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.After(s.held[target]) {
+		s.held[target] = t
+	}
+}
+
+// release the checks of target.
+func (s *Scheduler) release(target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.held, target)
+}
+
+// heldFor returns the time left until the checks of target are released.
+func (s *Scheduler) heldFor(target string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.held[target]
+	if !ok {
+		return 0
+	}
+
+	return t.Sub(s.clock.Now())
+}
+
+// backoff returns the interval doubled for each failure, up to the max
+// backoff, though never less than the interval.
+func (s *Scheduler) backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+
+	return max(min(d, s.maxBackoff), interval)
+}
+
+// jitterOf returns a random jitter of d.
+func (s *Scheduler) jitterOf(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * s.jitter * s.rand())
+}
+
+// targetOf returns the host of the URL of c, or the URL without one.
+func targetOf(c *httpstat.Check) string {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Host == "" {
+		return c.URL
+	}
+
+	return u.Host
+}
@@ -0,0 +1,346 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+	"github.com/apex/httpstat/scheduler"
+)
+
+// clock is a fake clock advanced manually.
+type clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waits   []time.Duration
+	waiters []waiter
+}
+
+// waiter is a pending After of a clock.
+type waiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// Now implementation.
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After implementation.
+func (c *clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, waiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance the clock by d, firing the waiters due.
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var pending []waiter
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.c <- c.now
+	}
+
+	c.waiters = pending
+}
+
+// BlockUntil blocks until n waiters are pending, returning the waits requested.
+func (c *clock) BlockUntil(t testing.TB, n int) []time.Duration {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		pending, waits := len(c.waiters), append([]time.Duration(nil), c.waits...)
+		c.mu.Unlock()
+
+		if pending >= n {
+			return waits
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d waiters", n)
+	return nil
+}
+
+// collect returns a sink sending results to a channel.
+func collect() (scheduler.Sink, chan scheduler.Result) {
+	ch := make(chan scheduler.Result, 10)
+	return scheduler.SinkFunc(func(r scheduler.Result) { ch <- r }), ch
+}
+
+func TestScheduler_Run(t *testing.T) {
+	t.Run("with backoff after failures", func(t *testing.T) {
+		var requests atomic.Int32
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) <= 2 {
+				w.WriteHeader(500)
+			}
+		}))
+		defer s.Close()
+
+		clk := &clock{now: time.Unix(0, 0)}
+		sink, results := collect()
+
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL, Interval: time.Minute}},
+			scheduler.WithClock(clk),
+			scheduler.WithJitter(0),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			v.Run(ctx)
+			close(done)
+		}()
+
+		r := <-results
+		assert.Equal(t, 1, r.Failures)
+		assert.Equal(t, 500, r.Stats.Status)
+		assert.Equal(t, time.Unix(0, 0), r.Time)
+
+		var e *httpstat.AssertionError
+		assert.True(t, errors.As(r.Err, &e), "assertion error")
+
+		assert.Equal(t, []time.Duration{0, 2 * time.Minute}, clk.BlockUntil(t, 1))
+		clk.Advance(time.Minute)
+		assert.Len(t, clk.BlockUntil(t, 1), 2)
+		clk.Advance(time.Minute)
+
+		r = <-results
+		assert.Equal(t, 2, r.Failures)
+
+		assert.Equal(t, 4*time.Minute, clk.BlockUntil(t, 1)[2])
+		clk.Advance(4 * time.Minute)
+
+		r = <-results
+		assert.NoError(t, r.Err, "run")
+		assert.Equal(t, 0, r.Failures)
+		assert.Equal(t, 200, r.Stats.Status)
+		assert.Equal(t, time.Minute, clk.BlockUntil(t, 1)[3])
+
+		cancel()
+		<-done
+	})
+
+	t.Run("with an unreachable target", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		s.Close()
+
+		clk := &clock{}
+		sink, results := collect()
+
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL + "/a", Interval: time.Minute}, {URL: s.URL + "/b", Interval: 10 * time.Second}},
+			scheduler.WithClock(clk),
+			scheduler.WithJitter(0),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go v.Run(ctx)
+
+		for range 2 {
+			r := <-results
+			assert.Nil(t, r.Stats, "stats")
+			assert.Equal(t, 1, r.Failures)
+		}
+
+		waits := clk.BlockUntil(t, 2)
+		slices.Sort(waits)
+		assert.Equal(t, []time.Duration{0, 0, 20 * time.Second, 2 * time.Minute}, waits)
+
+		clk.Advance(20 * time.Second)
+		assert.Equal(t, 100*time.Second, clk.BlockUntil(t, 2)[4])
+		assert.Empty(t, results, "results")
+	})
+
+	t.Run("with a reachable target", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/a" {
+				w.WriteHeader(500)
+			}
+		}))
+		defer s.Close()
+
+		clk := &clock{}
+		sink, results := collect()
+
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL + "/a", Interval: time.Minute}, {URL: s.URL + "/b", Interval: 10 * time.Second}},
+			scheduler.WithClock(clk),
+			scheduler.WithJitter(0),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go v.Run(ctx)
+
+		<-results
+		<-results
+
+		waits := clk.BlockUntil(t, 2)
+		slices.Sort(waits)
+		assert.Equal(t, []time.Duration{0, 0, 10 * time.Second, 2 * time.Minute}, waits)
+
+		clk.Advance(10 * time.Second)
+		r := <-results
+		assert.NoError(t, r.Err, "run")
+		assert.Equal(t, s.URL+"/b", r.Check.URL)
+	})
+
+	t.Run("with invalid options", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer s.Close()
+
+		clk := &clock{}
+		sink, results := collect()
+
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL}},
+			scheduler.WithClock(clk),
+			scheduler.WithInterval(0),
+			scheduler.WithJitter(-1),
+			scheduler.WithRand(func() float64 { return 0.5 }),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go v.Run(ctx)
+
+		<-results
+		assert.Equal(t, []time.Duration{0, time.Minute}, clk.BlockUntil(t, 1))
+	})
+
+	t.Run("with jitter", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer s.Close()
+
+		clk := &clock{}
+		sink, results := collect()
+
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL}},
+			scheduler.WithClock(clk),
+			scheduler.WithInterval(time.Minute),
+			scheduler.WithJitter(0.2),
+			scheduler.WithRand(func() float64 { return 0.5 }),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go v.Run(ctx)
+
+		assert.Equal(t, []time.Duration{6 * time.Second}, clk.BlockUntil(t, 1))
+		clk.Advance(6 * time.Second)
+		<-results
+		assert.Equal(t, 66*time.Second, clk.BlockUntil(t, 1)[1])
+	})
+
+	t.Run("with a concurrency cap", func(t *testing.T) {
+		var inflight, peak atomic.Int32
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inflight.Add(1)
+			defer inflight.Add(-1)
+
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+
+			time.Sleep(50 * time.Millisecond)
+		}))
+		defer s.Close()
+
+		var checks []*httpstat.Check
+		for range 4 {
+			checks = append(checks, &httpstat.Check{URL: s.URL})
+		}
+
+		sink, results := collect()
+		v := scheduler.New(checks,
+			scheduler.WithClock(&clock{}),
+			scheduler.WithJitter(0),
+			scheduler.WithConcurrency(2),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go v.Run(ctx)
+
+		for range 4 {
+			r := <-results
+			assert.NoError(t, r.Err, "run")
+		}
+
+		assert.Equal(t, int32(2), peak.Load())
+	})
+
+	t.Run("with a graceful shutdown", func(t *testing.T) {
+		entered := make(chan struct{})
+		release := make(chan struct{})
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(entered)
+			<-release
+		}))
+		defer s.Close()
+
+		sink, results := collect()
+		v := scheduler.New([]*httpstat.Check{{URL: s.URL}},
+			scheduler.WithClock(&clock{}),
+			scheduler.WithJitter(0),
+			scheduler.WithSink(sink))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			v.Run(ctx)
+			close(done)
+		}()
+
+		<-entered
+		cancel()
+
+		select {
+		case <-done:
+			t.Fatal("returned before the run completed")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-done
+
+		r := <-results
+		assert.NoError(t, r.Err, "run")
+		assert.Equal(t, 200, r.Stats.Status)
+	})
+}
@@ -0,0 +1,342 @@
+package httpstat
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Security header statuses.
+const (
+	HeaderPresent       = "present"
+	HeaderMissing       = "missing"
+	HeaderMisconfigured = "misconfigured"
+)
+
+// minHSTSMaxAge is the minimum HSTS max-age, 180 days.
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// securityHeaders are the headers analyzed, with the
+// score deducted when missing, or half when misconfigured.
+var securityHeaders = []struct {
+	name   string
+	weight int
+	check  func(v string) []string
+}{
+	{"Strict-Transport-Security", 25, checkHSTS},
+	{"Content-Security-Policy", 20, checkCSP},
+	{"X-Frame-Options", 15, checkFrameOptions},
+	{"X-Content-Type-Options", 15, checkContentTypeOptions},
+	{"Referrer-Policy", 10, checkReferrerPolicy},
+}
+
+// Score deductions.
+const (
+	cookieWeight    = 10
+	downgradeWeight = 25
+)
+
+// SecurityReport is the analysis of the security headers,
+// cookies and redirects of a response.
+type SecurityReport struct {
+	// Score from 0 to 100, deducting from 100 for each
+	// missing or misconfigured header, cookie or redirect.
+	Score int `json:"score"`
+
+	// URL of the final response.
+	URL string `json:"url"`
+
+	// Headers of the final response analyzed.
+	Headers []SecurityHeader `json:"headers"`
+
+	// Cookies set by any response.
+	Cookies []SecurityCookie `json:"cookies,omitempty"`
+
+	// UpgradedToHTTPS is true when a plaintext request
+	// was redirected to HTTPS.
+	UpgradedToHTTPS bool `json:"upgraded_to_https"`
+
+	// Downgraded is true when any redirect was from HTTPS to plaintext.
+	Downgraded bool `json:"downgraded"`
+
+	// Issues of the redirects.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// SecurityHeader is the analysis of a security header.
+type SecurityHeader struct {
+	// Name of the header.
+	Name string `json:"name"`
+
+	// Status such as HeaderPresent.
+	Status string `json:"status"`
+
+	// Value of the header.
+	Value string `json:"value,omitempty"`
+
+	// Issues when missing or misconfigured.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// SecurityCookie is the analysis of a cookie set by a response.
+type SecurityCookie struct {
+	// Name of the cookie.
+	Name string `json:"name"`
+
+	// URL of the response setting the cookie.
+	URL string `json:"url"`
+
+	// Status such as HeaderPresent, or HeaderMisconfigured.
+	Status string `json:"status"`
+
+	// Issues when misconfigured.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// AnalyzeSecurity analyzes the security headers of the final response
+// of r, the cookies set by each of its responses, and its redirects.
+func AnalyzeSecurity(r Response) *SecurityReport {
+	traces := r.Traces()
+	last := traces[len(traces)-1]
+	header := last.Header()
+	if header == nil {
+		header = r.Header()
+	}
+
+	v := &SecurityReport{
+		Score: 100,
+		URL:   last.URL(),
+	}
+
+	for _, sh := range securityHeaders {
+		h := SecurityHeader{
+			Name:   sh.name,
+			Status: HeaderPresent,
+			Value:  header.Get(sh.name),
+		}
+
+		switch {
+		case sh.name == "Strict-Transport-Security" && scheme(v.URL) != "https":
+			h.Status = HeaderMissing
+			h.Issues = []string{"response is not over HTTPS"}
+		case h.Value == "" && sh.name == "X-Frame-Options" && frameAncestors(header):
+			// superseded by the frame-ancestors of the CSP
+		case h.Value == "":
+			h.Status = HeaderMissing
+		default:
+			if h.Issues = sh.check(h.Value); len(h.Issues) > 0 {
+				h.Status = HeaderMisconfigured
+			}
+		}
+
+		switch h.Status {
+		case HeaderMissing:
+			v.Score -= sh.weight
+		case HeaderMisconfigured:
+			v.Score -= sh.weight / 2
+		}
+
+		v.Headers = append(v.Headers, h)
+	}
+
+	insecureCookies := false
+	for _, t := range traces {
+		for _, line := range t.Header().Values("Set-Cookie") {
+			c := analyzeCookie(t.URL(), line)
+			if c.Status == HeaderMisconfigured {
+				insecureCookies = true
+			}
+			v.Cookies = append(v.Cookies, c)
+		}
+	}
+
+	if insecureCookies {
+		v.Score -= cookieWeight
+	}
+
+	for i := 1; i < len(traces); i++ {
+		from, to := traces[i-1].URL(), traces[i].URL()
+
+		if scheme(from) == "https" && scheme(to) == "http" {
+			v.Downgraded = true
+			v.Issues = append(v.Issues, "redirected from "+from+" to plaintext "+to)
+		}
+	}
+
+	if v.Downgraded {
+		v.Score -= downgradeWeight
+	}
+
+	if scheme(traces[0].URL()) == "http" {
+		v.UpgradedToHTTPS = scheme(v.URL) == "https"
+		if !v.UpgradedToHTTPS {
+			v.Issues = append(v.Issues, "plaintext request was not redirected to HTTPS")
+		}
+	}
+
+	v.Score = max(v.Score, 0)
+
+	return v
+}
+
+// checkHSTS checks a Strict-Transport-Security header.
+func checkHSTS(v string) (issues []string) {
+	maxAge := -1
+
+	for _, d := range directives(v, ";") {
+		name, value, _ := strings.Cut(d, "=")
+		if strings.EqualFold(name, "max-age") {
+			n, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err == nil {
+				maxAge = n
+			}
+		}
+	}
+
+	switch {
+	case maxAge < 0:
+		issues = append(issues, "max-age is missing or invalid")
+	case maxAge < minHSTSMaxAge:
+		issues = append(issues, "max-age is less than 180 days")
+	}
+
+	return
+}
+
+// checkCSP checks a Content-Security-Policy header.
+func checkCSP(v string) (issues []string) {
+	for _, d := range directives(v, ";") {
+		name, sources, _ := strings.Cut(d, " ")
+		if name != "default-src" && name != "script-src" {
+			continue
+		}
+
+		for _, s := range strings.Fields(sources) {
+			switch s {
+			case "'unsafe-inline'", "'unsafe-eval'":
+				issues = append(issues, name+" allows "+s)
+			case "*":
+				issues = append(issues, name+" allows any source")
+			}
+		}
+	}
+
+	return
+}
+
+// checkFrameOptions checks an X-Frame-Options header.
+func checkFrameOptions(v string) []string {
+	switch strings.ToUpper(strings.TrimSpace(v)) {
+	case "DENY", "SAMEORIGIN":
+		return nil
+	}
+
+	return []string{"value is not DENY or SAMEORIGIN"}
+}
+
+// checkContentTypeOptions checks an X-Content-Type-Options header.
+func checkContentTypeOptions(v string) []string {
+	if strings.EqualFold(strings.TrimSpace(v), "nosniff") {
+		return nil
+	}
+
+	return []string{"value is not nosniff"}
+}
+
+// checkReferrerPolicy checks a Referrer-Policy header,
+// the last recognized policy of which applies.
+func checkReferrerPolicy(v string) []string {
+	policy := ""
+
+	for _, p := range directives(v, ",") {
+		switch p = strings.ToLower(p); p {
+		case "no-referrer", "no-referrer-when-downgrade", "origin",
+			"origin-when-cross-origin", "same-origin", "strict-origin",
+			"strict-origin-when-cross-origin", "unsafe-url":
+			policy = p
+		}
+	}
+
+	switch policy {
+	case "":
+		return []string{"policy is not recognized"}
+	case "unsafe-url", "no-referrer-when-downgrade":
+		return []string{"policy " + policy + " leaks URLs to other origins"}
+	}
+
+	return nil
+}
+
+// frameAncestors returns true if the Content-Security-Policy
+// of h sets frame-ancestors, which supersedes X-Frame-Options.
+func frameAncestors(h http.Header) bool {
+	for _, d := range directives(h.Get("Content-Security-Policy"), ";") {
+		if name, _, _ := strings.Cut(d, " "); name == "frame-ancestors" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// analyzeCookie analyzes the Set-Cookie line of the response to uri.
+func analyzeCookie(uri, line string) SecurityCookie {
+	v := SecurityCookie{
+		URL:    uri,
+		Status: HeaderPresent,
+	}
+
+	c, err := http.ParseSetCookie(line)
+	if err != nil {
+		v.Status = HeaderMisconfigured
+		v.Issues = []string{err.Error()}
+		return v
+	}
+
+	v.Name = c.Name
+
+	if !c.Secure {
+		v.Issues = append(v.Issues, "Secure is not set")
+	}
+
+	if !c.HttpOnly {
+		v.Issues = append(v.Issues, "HttpOnly is not set")
+	}
+
+	switch c.SameSite {
+	case 0, http.SameSiteDefaultMode:
+		v.Issues = append(v.Issues, "SameSite is not set")
+	case http.SameSiteNoneMode:
+		if !c.Secure {
+			v.Issues = append(v.Issues, "SameSite=None requires Secure")
+		}
+	}
+
+	if len(v.Issues) > 0 {
+		v.Status = HeaderMisconfigured
+	}
+
+	return v
+}
+
+// directives splits v by sep, trimming space and omitting empty directives.
+func directives(v, sep string) (d []string) {
+	for _, s := range strings.Split(v, sep) {
+		if s = strings.TrimSpace(s); s != "" {
+			d = append(d, s)
+		}
+	}
+
+	return
+}
+
+// scheme returns the scheme of uri.
+func scheme(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme
+}
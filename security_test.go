@@ -0,0 +1,144 @@
+package httpstat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// headers returns a handler responding with the given headers.
+func headers(h map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for name, v := range h {
+			w.Header().Set(name, v)
+		}
+		w.Write([]byte("hello world"))
+	}
+}
+
+// redirectTo returns a handler redirecting to uri, setting a cookie.
+func redirectTo(uri, cookie string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", cookie)
+		http.Redirect(w, r, uri, http.StatusMovedPermanently)
+	}
+}
+
+// statuses returns the status of each header of v.
+func statuses(v *httpstat.SecurityReport) map[string]string {
+	m := make(map[string]string)
+	for _, h := range v.Headers {
+		m[h.Name] = h.Status
+	}
+	return m
+}
+
+func TestAnalyzeSecurity(t *testing.T) {
+	t.Run("with a secure configuration", func(t *testing.T) {
+		s := httptest.NewTLSServer(headers(map[string]string{
+			"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+			"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'",
+			"X-Content-Type-Options":    "nosniff",
+			"Referrer-Policy":           "strict-origin-when-cross-origin",
+			"Set-Cookie":                "session=1; Secure; HttpOnly; SameSite=Lax",
+		}))
+		defer s.Close()
+
+		r := server(redirectTo(s.URL, "visited=1; Secure; HttpOnly; SameSite=Strict"))
+		defer r.Close()
+
+		res, err := httpstat.Request("GET", r.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, r.URL, res.Traces()[0].URL())
+		assert.Equal(t, 301, res.Traces()[0].Status())
+		assert.Equal(t, s.URL, res.Traces()[1].URL())
+
+		v := httpstat.AnalyzeSecurity(res)
+		assert.Equal(t, 100, v.Score)
+		assert.Equal(t, s.URL, v.URL)
+		assert.True(t, v.UpgradedToHTTPS, "upgraded")
+		assert.False(t, v.Downgraded, "downgraded")
+		assert.Empty(t, v.Issues, "issues")
+
+		for _, h := range v.Headers {
+			assert.Equal(t, httpstat.HeaderPresent, h.Status, h.Name)
+		}
+
+		assert.Len(t, v.Cookies, 2)
+		assert.Equal(t, "visited", v.Cookies[0].Name)
+		assert.Equal(t, r.URL, v.Cookies[0].URL)
+		assert.Equal(t, httpstat.HeaderPresent, v.Cookies[0].Status)
+		assert.Equal(t, "session", v.Cookies[1].Name)
+	})
+
+	t.Run("without security headers", func(t *testing.T) {
+		s := server(headers(map[string]string{
+			"Set-Cookie": "session=1",
+		}))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+
+		v := httpstat.AnalyzeSecurity(res)
+		assert.Equal(t, 5, v.Score)
+		assert.False(t, v.UpgradedToHTTPS, "upgraded")
+		assert.Equal(t, []string{"plaintext request was not redirected to HTTPS"}, v.Issues)
+
+		for _, h := range v.Headers {
+			assert.Equal(t, httpstat.HeaderMissing, h.Status, h.Name)
+		}
+
+		assert.Equal(t, []string{"response is not over HTTPS"}, v.Headers[0].Issues)
+		assert.Equal(t, httpstat.HeaderMisconfigured, v.Cookies[0].Status)
+		assert.Equal(t, []string{"Secure is not set", "HttpOnly is not set", "SameSite is not set"}, v.Cookies[0].Issues)
+	})
+
+	t.Run("with misconfigured headers", func(t *testing.T) {
+		s := httptest.NewTLSServer(headers(map[string]string{
+			"Strict-Transport-Security": "max-age=60",
+			"Content-Security-Policy":   "script-src 'self' 'unsafe-inline'",
+			"X-Frame-Options":           "ALLOW-FROM https://example.com",
+			"X-Content-Type-Options":    "sniff",
+			"Referrer-Policy":           "unsafe-url",
+		}))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+
+		v := httpstat.AnalyzeSecurity(res)
+		assert.Equal(t, 59, v.Score)
+
+		for _, h := range v.Headers {
+			assert.Equal(t, httpstat.HeaderMisconfigured, h.Status, h.Name)
+			assert.Len(t, h.Issues, 1, h.Name)
+		}
+
+		assert.Equal(t, []string{"max-age is less than 180 days"}, v.Headers[0].Issues)
+		assert.Equal(t, []string{"script-src allows 'unsafe-inline'"}, v.Headers[1].Issues)
+	})
+
+	t.Run("with a downgrade", func(t *testing.T) {
+		r := server(headers(nil))
+		defer r.Close()
+
+		s := httptest.NewTLSServer(redirectTo(r.URL, "session=1; Secure; HttpOnly; SameSite=None"))
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+
+		v := httpstat.AnalyzeSecurity(res)
+		assert.True(t, v.Downgraded, "downgraded")
+		assert.False(t, v.UpgradedToHTTPS, "upgraded")
+		assert.Equal(t, []string{"redirected from " + s.URL + " to plaintext " + r.URL}, v.Issues)
+		assert.Equal(t, httpstat.HeaderPresent, v.Cookies[0].Status)
+		assert.Equal(t, 0, v.Score)
+		assert.Equal(t, httpstat.HeaderMissing, statuses(v)["X-Frame-Options"])
+	})
+}
@@ -0,0 +1,188 @@
+package httpstat
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 protocol, see RFC 1928 and RFC 1929.
+const (
+	socksVersion  = 0x05
+	socksNoAuth   = 0x00
+	socksPassword = 0x02
+	socksConnect  = 0x01
+	socksIPv4     = 0x01
+	socksDomain   = 0x03
+	socksIPv6     = 0x04
+)
+
+// SOCKS errors.
+var (
+	errSOCKSAuth      = errors.New("SOCKS authentication failed")
+	errSOCKSMethod    = errors.New("SOCKS authentication method not supported")
+	errSOCKSMalformed = errors.New("malformed SOCKS reply")
+)
+
+// SOCKSAuth is the username and password of a SOCKS5 proxy.
+type SOCKSAuth struct {
+	Username string
+	Password string
+}
+
+// socksReply is an unsuccessful SOCKS5 reply code.
+type socksReply byte
+
+// Error implementation.
+func (r socksReply) Error() string {
+	return fmt.Sprintf("SOCKS reply %d", byte(r))
+}
+
+// socks is a SOCKS5 proxy.
+type socks struct {
+	addr string
+	auth *SOCKSAuth
+}
+
+// connect negotiates a connection to addr over c, which is connected to
+// the proxy. Hosts are sent to the proxy to resolve, as with socks5h.
+func (s *socks) connect(ctx context.Context, c net.Conn, addr string) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	err := s.negotiate(c, addr)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+// negotiate the authentication method and connection.
+func (s *socks) negotiate(c net.Conn, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	portnum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return &net.AddrError{Err: "invalid port", Addr: addr}
+	}
+
+	methods := []byte{socksNoAuth}
+	if s.auth != nil {
+		methods = append(methods, socksPassword)
+	}
+
+	if _, err := c.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	var b [4]byte
+	if _, err := io.ReadFull(c, b[:2]); err != nil {
+		return err
+	}
+
+	if b[0] != socksVersion {
+		return errSOCKSMalformed
+	}
+
+	switch {
+	case b[1] == socksNoAuth:
+	case b[1] == socksPassword && s.auth != nil:
+		if err := s.authenticate(c); err != nil {
+			return err
+		}
+	default:
+		return errSOCKSMethod
+	}
+
+	req := []byte{socksVersion, socksConnect, 0}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip.To4() != nil:
+		req = append(req, socksIPv4)
+		req = append(req, ip.To4()...)
+	case ip != nil:
+		req = append(req, socksIPv6)
+		req = append(req, ip...)
+	case len(host) > 255:
+		return &net.AddrError{Err: "host name too long", Addr: host}
+	default:
+		req = append(req, socksDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+
+	req = binary.BigEndian.AppendUint16(req, uint16(portnum))
+
+	if _, err := c.Write(req); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(c, b[:4]); err != nil {
+		return err
+	}
+
+	if b[0] != socksVersion {
+		return errSOCKSMalformed
+	}
+
+	if b[1] != 0 {
+		return socksReply(b[1])
+	}
+
+	// skip the bound address and port
+	var n int
+	switch b[3] {
+	case socksIPv4:
+		n = net.IPv4len
+	case socksIPv6:
+		n = net.IPv6len
+	case socksDomain:
+		if _, err := io.ReadFull(c, b[:1]); err != nil {
+			return err
+		}
+		n = int(b[0])
+	default:
+		return errSOCKSMalformed
+	}
+
+	_, err = io.CopyN(io.Discard, c, int64(n+2))
+	return err
+}
+
+// authenticate with the username and password.
+func (s *socks) authenticate(c net.Conn) error {
+	if len(s.auth.Username) > 255 || len(s.auth.Password) > 255 {
+		return errSOCKSAuth
+	}
+
+	req := []byte{0x01, byte(len(s.auth.Username))}
+	req = append(req, s.auth.Username...)
+	req = append(req, byte(len(s.auth.Password)))
+	req = append(req, s.auth.Password...)
+
+	if _, err := c.Write(req); err != nil {
+		return err
+	}
+
+	var b [2]byte
+	if _, err := io.ReadFull(c, b[:]); err != nil {
+		return err
+	}
+
+	if b[1] != 0 {
+		return errSOCKSAuth
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package httpstat_test
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// socksServer serves SOCKS5 connect requests, requiring the given
+// password when not empty, and replying with reply.
+func socksServer(t testing.TB, password string, reply byte) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err, "listen")
+	t.Cleanup(func() { l.Close() })
+
+	// read returns the next n bytes, or the length-prefixed bytes when n is zero
+	read := func(c net.Conn, n int) []byte {
+		b := make([]byte, 1)
+		if n == 0 {
+			io.ReadFull(c, b)
+			n = int(b[0])
+		}
+		b = make([]byte, n)
+		io.ReadFull(c, b)
+		return b
+	}
+
+	serve := func(c net.Conn) {
+		defer c.Close()
+
+		read(c, 1)
+		read(c, 0)
+
+		if password == "" {
+			c.Write([]byte{5, 0})
+		} else {
+			c.Write([]byte{5, 2})
+			read(c, 1)
+			read(c, 0)
+			if string(read(c, 0)) != password {
+				c.Write([]byte{1, 1})
+				return
+			}
+			c.Write([]byte{1, 0})
+		}
+
+		var host string
+		switch read(c, 4)[3] {
+		case 1:
+			host = net.IP(read(c, 4)).String()
+		case 3:
+			host = string(read(c, 0))
+		}
+		b := read(c, 2)
+		port := int(b[0])<<8 | int(b[1])
+
+		time.Sleep(10 * time.Millisecond)
+		c.Write([]byte{5, reply, 0, 1, 127, 0, 0, 1, 0, 0})
+		if reply != 0 {
+			return
+		}
+
+		origin, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return
+		}
+		defer origin.Close()
+
+		go io.Copy(origin, c)
+		io.Copy(c, origin)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestResponse_SOCKS5(t *testing.T) {
+	s := server(noRedirects)
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	uri := "http://" + net.JoinHostPort("localhost", port)
+
+	t.Run("without auth", func(t *testing.T) {
+		addr := socksServer(t, "", 0)
+
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithSOCKS5(addr, nil))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.True(t, res.TimeSOCKS() >= 10*time.Millisecond, "socks")
+		assert.Equal(t, res.TimeSOCKS(), res.Stats().TimeSOCKS)
+		assert.Zero(t, res.TimeDNS(), "dns")
+	})
+
+	t.Run("with auth", func(t *testing.T) {
+		addr := socksServer(t, "ferret", 0)
+
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithSOCKS5(addr, &httpstat.SOCKSAuth{
+			Username: "tobi",
+			Password: "ferret",
+		}))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 200, res.Status(), "status")
+		assert.True(t, res.TimeSOCKS() >= 10*time.Millisecond, "socks")
+	})
+
+	t.Run("with invalid auth", func(t *testing.T) {
+		addr := socksServer(t, "ferret", 0)
+
+		_, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithSOCKS5(addr, &httpstat.SOCKSAuth{
+			Username: "tobi",
+			Password: "loki",
+		}))
+		assert.EqualError(t, err, "SOCKS authentication failed")
+	})
+
+	t.Run("without required auth", func(t *testing.T) {
+		addr := socksServer(t, "ferret", 0)
+
+		_, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithSOCKS5(addr, nil))
+		assert.EqualError(t, err, "SOCKS authentication method not supported")
+	})
+
+	t.Run("with a failed connection", func(t *testing.T) {
+		addr := socksServer(t, "", 5)
+
+		_, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithSOCKS5(addr, nil))
+		assert.EqualError(t, err, "SOCKS connection refused")
+	})
+
+	t.Run("without a proxy", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil)
+		assert.NoError(t, err, "request")
+		assert.Zero(t, res.TimeSOCKS(), "socks")
+	})
+}
@@ -0,0 +1,54 @@
+package httpstat
+
+import (
+	"crypto/tls"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpInfo returns the kernel TCP information of c, or nil.
+func tcpInfo(c net.Conn) *TCPInfo {
+	// unwrap TLS, including that of an HTTPS proxy tunneled through
+	for {
+		switch v := c.(type) {
+		case *tls.Conn:
+			c = v.NetConn()
+			continue
+		case *bufferedConn:
+			c = v.Conn
+			continue
+		}
+		break
+	}
+
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var info *unix.TCPInfo
+	err = rc.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	})
+
+	if err != nil || info == nil {
+		return nil
+	}
+
+	return &TCPInfo{
+		RTT:              time.Duration(info.Rtt) * time.Microsecond,
+		RTTVar:           time.Duration(info.Rttvar) * time.Microsecond,
+		Retransmits:      int(info.Total_retrans),
+		CongestionWindow: int(info.Snd_cwnd),
+		MSS:              int(info.Snd_mss),
+		DeliveryRate:     info.Delivery_rate,
+	}
+}
@@ -0,0 +1,28 @@
+package httpstat_test
+
+import (
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+func TestResponse_TCPInfo(t *testing.T) {
+	s := server(redirects)
+	defer s.Close()
+
+	res, err := httpstat.Request("GET", s.URL, nil, nil)
+	assert.NoError(t, err, "request")
+	assert.Len(t, res.Traces(), 3)
+
+	for _, trace := range res.Traces() {
+		info := trace.TCPInfo()
+		assert.NotNil(t, info, "tcp info")
+		assert.NotZero(t, info.RTT, "rtt")
+		assert.NotZero(t, info.MSS, "mss")
+		assert.NotZero(t, info.CongestionWindow, "congestion window")
+	}
+
+	assert.Equal(t, res.Traces()[2].TCPInfo(), res.Stats().Traces[2].TCPInfo)
+}
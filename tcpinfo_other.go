@@ -0,0 +1,13 @@
+//go:build !linux
+
+package httpstat
+
+import (
+	"net"
+)
+
+// tcpInfo returns the kernel TCP information of c, which
+// is only supported on Linux.
+func tcpInfo(c net.Conn) *TCPInfo {
+	return nil
+}
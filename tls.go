@@ -0,0 +1,54 @@
+package httpstat
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+// clientCertificate returns a GetClientCertificate function choosing
+// a certificate as config would, and recording in the trace of the
+// handshake that a client certificate was requested, and if one was sent.
+func clientCertificate(config *tls.Config) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	get := config.GetClientCertificate
+	certs := config.Certificates
+
+	return func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		cert := &tls.Certificate{}
+		var err error
+
+		if get != nil {
+			cert, err = get(info)
+		} else {
+			for i := range certs {
+				if info.SupportsCertificate(&certs[i]) == nil {
+					cert = &certs[i]
+					break
+				}
+			}
+		}
+
+		if t := traceFromContext(info.Context()); t != nil {
+			t.clientCertRequested = true
+			t.clientCertSent = err == nil && cert != nil && len(cert.Certificate) > 0
+		}
+
+		return cert, err
+	}
+}
+
+// loadClientCertificate returns a GetClientCertificate function
+// loading the PEM encoded certificate and key files once, when
+// a certificate is first requested.
+func loadClientCertificate(certFile, keyFile string) func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	load := sync.OnceValues(func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &cert, nil
+	})
+
+	return func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return load()
+	}
+}
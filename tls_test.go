@@ -0,0 +1,217 @@
+package httpstat_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// clientCertificate returns a self-signed client certificate,
+// writing it and its key to PEM files in a temporary directory.
+func clientCertificate(t testing.TB) (cert tls.Certificate, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tobi"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err, "certificate")
+
+	b, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err, "marshal")
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0600), "write")
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0600), "write")
+
+	cert, err = tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err, "key pair")
+
+	return
+}
+
+// mtlsServer returns a TLS server with the given client authentication,
+// responding with the number of client certificates received.
+func mtlsServer(auth tls.ClientAuthType) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, len(r.TLS.PeerCertificates))
+	}))
+	s.TLS = &tls.Config{ClientAuth: auth}
+	s.StartTLS()
+	return s
+}
+
+func TestResponse_ClientCert(t *testing.T) {
+	cert, certFile, keyFile := clientCertificate(t)
+
+	t.Run("with a certificate", func(t *testing.T) {
+		s := mtlsServer(tls.RequireAnyClientCert)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithClientCertificate(cert))
+		assert.NoError(t, err, "request")
+		assert.Equal(t, 1, res.BodySize(), "body size")
+
+		trace := res.Traces()[0]
+		assert.True(t, trace.ClientCertRequested(), "requested")
+		assert.True(t, trace.ClientCertSent(), "sent")
+		assert.True(t, res.Stats().Traces[0].ClientCertSent, "sent")
+	})
+
+	t.Run("with certificate files", func(t *testing.T) {
+		s := mtlsServer(tls.RequireAnyClientCert)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithClientCertificateFile(certFile, keyFile))
+		assert.NoError(t, err, "request")
+		assert.True(t, res.Traces()[0].ClientCertSent(), "sent")
+	})
+
+	t.Run("with missing certificate files", func(t *testing.T) {
+		s := mtlsServer(tls.RequireAnyClientCert)
+		defer s.Close()
+
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithClientCertificateFile(certFile+".missing", keyFile))
+		assert.Error(t, err, "request")
+		assert.Contains(t, err.Error(), "no such file or directory")
+	})
+
+	t.Run("without a certificate", func(t *testing.T) {
+		s := mtlsServer(tls.RequestClientCert)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+
+		trace := res.Traces()[0]
+		assert.True(t, trace.ClientCertRequested(), "requested")
+		assert.False(t, trace.ClientCertSent(), "sent")
+	})
+
+	t.Run("when not requested", func(t *testing.T) {
+		s := mtlsServer(tls.NoClientCert)
+		defer s.Close()
+
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithClientCertificate(cert))
+		assert.NoError(t, err, "request")
+
+		trace := res.Traces()[0]
+		assert.False(t, trace.ClientCertRequested(), "requested")
+		assert.False(t, trace.ClientCertSent(), "sent")
+	})
+}
+
+func TestResponse_TLSOptions(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	uri := "https://" + net.JoinHostPort("localhost", port)
+
+	t.Run("without root CAs", func(t *testing.T) {
+		_, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithProtocol(httpstat.ProtocolHTTP1))
+		assert.Error(t, err, "request")
+		assert.Contains(t, err.Error(), "unknown authority")
+	})
+
+	t.Run("with root CAs", func(t *testing.T) {
+		res, err := httpstat.Request("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+		assert.True(t, res.TLS(), "tls")
+	})
+
+	t.Run("with insecure", func(t *testing.T) {
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithInsecure(true))
+		assert.NoError(t, err, "request")
+		assert.True(t, res.TLS(), "tls")
+	})
+
+	t.Run("without a server name", func(t *testing.T) {
+		_, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.Error(t, err, "request")
+		assert.Contains(t, err.Error(), "not localhost")
+	})
+
+	t.Run("with a server name", func(t *testing.T) {
+		res, err := httpstat.Request("GET", uri, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithServerName("example.com"))
+		assert.NoError(t, err, "request")
+		assert.True(t, res.TLS(), "tls")
+	})
+}
+
+func TestRequestResumption(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(noRedirects))
+	defer s.Close()
+
+	t.Run("with session tickets", func(t *testing.T) {
+		v, err := httpstat.RequestResumption("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+
+		assert.False(t, v.Full.Resumed(), "full")
+		assert.True(t, v.Resumed.Resumed(), "resumed")
+		assert.True(t, v.Resumed.Stats().Resumed, "resumed")
+		assert.True(t, v.DidResume, "resumed")
+		assert.False(t, v.Used0RTT, "0-RTT")
+		assert.NotZero(t, v.Full.TimeTLS(), "full")
+		assert.NotZero(t, v.Resumed.TimeTLS(), "resumed")
+		assert.Equal(t, v.Full.TimeTLS()-v.Resumed.TimeTLS(), v.Savings)
+	})
+
+	t.Run("without session tickets", func(t *testing.T) {
+		s := httptest.NewUnstartedServer(http.HandlerFunc(noRedirects))
+		s.TLS = &tls.Config{SessionTicketsDisabled: true}
+		s.StartTLS()
+		defer s.Close()
+
+		v, err := httpstat.RequestResumption("GET", s.URL, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs))
+		assert.NoError(t, err, "request")
+		assert.False(t, v.DidResume, "resumed")
+	})
+
+	t.Run("with HTTP/3", func(t *testing.T) {
+		qs, port := quicServer(t, s, noRedirects)
+		defer qs.Close()
+
+		uri := fmt.Sprintf("https://127.0.0.1:%d", port)
+
+		v, err := httpstat.RequestResumption("GET", uri, nil, nil, httpstat.WithRootCAs(rootCAs(s).RootCAs), httpstat.WithProtocol(httpstat.ProtocolHTTP3))
+		assert.NoError(t, err, "request")
+		assert.False(t, v.Full.Resumed(), "full")
+		assert.True(t, v.DidResume, "resumed")
+		assert.True(t, v.Used0RTT, "0-RTT")
+	})
+
+	t.Run("with a failed request", func(t *testing.T) {
+		_, err := httpstat.RequestResumption("GET", s.URL, nil, nil)
+		assert.Error(t, err, "request")
+	})
+}
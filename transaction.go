@@ -0,0 +1,322 @@
+package httpstat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStepBody is the max size of the response body of a step
+// from which values are extracted.
+const maxStepBody = 10 << 20
+
+// variable matches a "{{name}}" variable reference.
+var variable = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Step is a request of a transaction. Its URL, header values and body may
+// reference variables extracted by previous steps, such as "{{token}}".
+type Step struct {
+	// Name of the step.
+	Name string `json:"name,omitempty"`
+
+	// Method of the request, defaulting to GET.
+	Method string `json:"method,omitempty"`
+
+	// URL of the request.
+	URL string `json:"url"`
+
+	// Header of the request.
+	Header http.Header `json:"header,omitempty"`
+
+	// Body of the request.
+	Body string `json:"body,omitempty"`
+
+	// Status expected, or any status below 400 when zero.
+	Status int `json:"status,omitempty"`
+
+	// Extract values from the response into variables.
+	Extract []Extract `json:"extract,omitempty"`
+}
+
+// Extract is a value extracted from a response into a variable.
+// The value is that of the Header, the JSON field of the body,
+// or the first match of Regex in the body, or in the header
+// value when Header is also set.
+type Extract struct {
+	// Var is the name of the variable.
+	Var string `json:"var"`
+
+	// Header name.
+	Header string `json:"header,omitempty"`
+
+	// JSON field path, with dots separating fields and array
+	// indices, such as "data.items.0.id".
+	JSON string `json:"json,omitempty"`
+
+	// Regex whose first group, or whole match, is the value.
+	Regex string `json:"regex,omitempty"`
+}
+
+// Transaction is the result of a sequence of steps.
+type Transaction struct {
+	// Steps performed.
+	Steps []StepResult `json:"steps"`
+
+	// Vars extracted.
+	Vars map[string]string `json:"vars,omitempty"`
+
+	// Time taken by all steps.
+	Time time.Duration `json:"time"`
+}
+
+// StepResult is the result of a step.
+type StepResult struct {
+	// Name of the step.
+	Name string `json:"name,omitempty"`
+
+	// Stats of the response.
+	Stats *Stats `json:"stats"`
+
+	// Time taken by the step, including redirects.
+	Time time.Duration `json:"time"`
+
+	// Response of the step.
+	Response Response `json:"-"`
+}
+
+// StepError is the error of a step of a transaction.
+type StepError struct {
+	// Step is the index of the step.
+	Step int
+
+	// Name of the step.
+	Name string
+
+	// Err of the step.
+	Err error
+}
+
+// Error implementation.
+func (e *StepError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("step %d: %s", e.Step, e.Err)
+	}
+
+	return fmt.Sprintf("step %d (%s): %s", e.Step, e.Name, e.Err)
+}
+
+// Unwrap implementation.
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// RunTransaction performs the steps in order with a client sharing a
+// cookie jar, unless another is given by WithCookieJar. It stops at the
+// first step which fails, returning a StepError along with the steps
+// performed, including the failing step when it was responded to.
+func RunTransaction(steps []Step, options ...Option) (*Transaction, error) {
+	jar, _ := cookiejar.New(nil)
+	client := NewClient(append([]Option{WithCookieJar(jar)}, options...)...)
+
+	v := &Transaction{
+		Vars: make(map[string]string),
+	}
+
+	start := time.Now()
+	defer func() { v.Time = time.Since(start) }()
+
+	for i, step := range steps {
+		r, err := v.run(client, step)
+		if r.Response != nil {
+			v.Steps = append(v.Steps, r)
+		}
+
+		if err != nil {
+			return v, &StepError{Step: i, Name: step.Name, Err: err}
+		}
+	}
+
+	return v, nil
+}
+
+// run performs step, extracting its variables.
+func (v *Transaction) run(client *http.Client, step Step) (StepResult, error) {
+	method := step.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	uri, err := v.expand(step.URL)
+	if err != nil {
+		return StepResult{}, err
+	}
+
+	header := make(http.Header)
+	for name, field := range step.Header {
+		for _, s := range field {
+			s, err := v.expand(s)
+			if err != nil {
+				return StepResult{}, err
+			}
+			header.Add(name, s)
+		}
+	}
+
+	var body io.Reader
+	if step.Body != "" {
+		s, err := v.expand(step.Body)
+		if err != nil {
+			return StepResult{}, err
+		}
+		body = strings.NewReader(s)
+	}
+
+	var b bytes.Buffer
+	start := time.Now()
+
+	res, err := request(context.Background(), client, method, uri, header, body, &limitWriter{&b, maxStepBody})
+	if err != nil {
+		return StepResult{}, err
+	}
+
+	r := StepResult{
+		Name:     step.Name,
+		Stats:    res.Stats(),
+		Time:     time.Since(start),
+		Response: res,
+	}
+
+	switch {
+	case step.Status != 0 && res.Status() != step.Status:
+		return r, fmt.Errorf("expected status %d, got %d", step.Status, res.Status())
+	case step.Status == 0 && res.Status() >= 400:
+		return r, fmt.Errorf("unexpected status %d", res.Status())
+	}
+
+	for _, e := range step.Extract {
+		s, err := extract(e, res.Header(), b.Bytes())
+		if err != nil {
+			return r, fmt.Errorf("extracting %q: %w", e.Var, err)
+		}
+		v.Vars[e.Var] = s
+	}
+
+	return r, nil
+}
+
+// expand the variables referenced by s.
+func (v *Transaction) expand(s string) (string, error) {
+	var err error
+
+	s = variable.ReplaceAllStringFunc(s, func(ref string) string {
+		name := variable.FindStringSubmatch(ref)[1]
+		value, ok := v.Vars[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("variable %q is not defined", name)
+		}
+		return value
+	})
+
+	return s, err
+}
+
+// extract the value of e from a response.
+func extract(e Extract, header http.Header, body []byte) (string, error) {
+	switch {
+	case e.Header != "" && e.Regex != "":
+		return match(e.Regex, header.Get(e.Header))
+	case e.Header != "":
+		if s := header.Get(e.Header); s != "" {
+			return s, nil
+		}
+		return "", fmt.Errorf("header %s is missing", e.Header)
+	case e.JSON != "":
+		return jsonField(e.JSON, body)
+	case e.Regex != "":
+		return match(e.Regex, string(body))
+	default:
+		return "", errors.New("header, json or regex is required")
+	}
+}
+
+// match returns the first group of the first match of
+// pattern in s, or the whole match without a group.
+func match(pattern, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	m := re.FindStringSubmatch(s)
+	switch {
+	case m == nil:
+		return "", fmt.Errorf("regex %q does not match", pattern)
+	case len(m) > 1:
+		return m[1], nil
+	default:
+		return m[0], nil
+	}
+}
+
+// jsonField returns the field of the JSON body at path.
+// Strings are returned as-is, other values as JSON,
+// with numbers as written so that large IDs are kept.
+func jsonField(path string, body []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("parsing JSON: %w", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			field, ok := node[key]
+			if !ok {
+				return "", fmt.Errorf("JSON field %q is missing", path)
+			}
+			v = field
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("JSON field %q is missing", path)
+			}
+			v = node[i]
+		default:
+			return "", fmt.Errorf("JSON field %q is missing", path)
+		}
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// limitWriter writes up to n bytes to w, discarding the rest.
+type limitWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+// Write implementation.
+func (w *limitWriter) Write(b []byte) (int, error) {
+	if room := w.n - w.w.Len(); room > 0 {
+		w.w.Write(b[:min(len(b), room)])
+	}
+
+	return len(b), nil
+}
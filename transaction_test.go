@@ -0,0 +1,127 @@
+package httpstat_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/httpstat"
+)
+
+// app is an application requiring a CSRF token to log in.
+func app(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/login":
+		if r.Method == "GET" {
+			w.Header().Set("X-Request-Id", "abc")
+			fmt.Fprint(w, `<input name="csrf" value="t0ken">`)
+			return
+		}
+
+		if r.FormValue("csrf") != "t0ken" {
+			http.Error(w, "invalid token", http.StatusForbidden)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		fmt.Fprint(w, `{"user": {"id": 5, "roles": ["admin"]}}`)
+	case "/users/5":
+		if _, err := r.Cookie("session"); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Role")))
+	case "/orders":
+		fmt.Fprint(w, `{"id": 12345678901234567890, "total": 1.50}`)
+	}
+}
+
+func TestRunTransaction(t *testing.T) {
+	s := server(app)
+	defer s.Close()
+
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+
+	t.Run("with all steps passing", func(t *testing.T) {
+		v, err := httpstat.RunTransaction([]httpstat.Step{
+			{
+				Name: "token",
+				URL:  s.URL + "/login",
+				Extract: []httpstat.Extract{
+					{Var: "csrf", Regex: `name="csrf" value="(\w+)"`},
+					{Var: "request", Header: "X-Request-Id"},
+				},
+			},
+			{
+				Name:   "login",
+				Method: "POST",
+				URL:    s.URL + "/login",
+				Header: form,
+				Body:   "csrf={{csrf}}",
+				Extract: []httpstat.Extract{
+					{Var: "id", JSON: "user.id"},
+					{Var: "role", JSON: "user.roles.0"},
+				},
+			},
+			{
+				Name:   "profile",
+				URL:    s.URL + "/users/{{ id }}",
+				Header: http.Header{"X-Role": {"{{role}}"}},
+				Status: 200,
+			},
+		})
+
+		assert.NoError(t, err, "transaction")
+		assert.Len(t, v.Steps, 3)
+		assert.Equal(t, map[string]string{"csrf": "t0ken", "request": "abc", "id": "5", "role": "admin"}, v.Vars)
+		assert.Equal(t, "profile", v.Steps[2].Name)
+		assert.Equal(t, 200, v.Steps[2].Stats.Status)
+		assert.Equal(t, len("admin"), v.Steps[2].Stats.BodySize)
+		assert.True(t, v.Time >= v.Steps[0].Time+v.Steps[1].Time+v.Steps[2].Time, "time")
+	})
+
+	t.Run("with a failing step", func(t *testing.T) {
+		v, err := httpstat.RunTransaction([]httpstat.Step{
+			{URL: s.URL + "/login"},
+			{Name: "login", Method: "POST", URL: s.URL + "/login", Header: form, Body: "csrf=invalid"},
+			{URL: s.URL + "/users/5"},
+		})
+
+		assert.EqualError(t, err, "step 1 (login): unexpected status 403")
+		assert.Len(t, v.Steps, 2)
+
+		var e *httpstat.StepError
+		assert.True(t, errors.As(err, &e), "step error")
+		assert.Equal(t, 1, e.Step)
+	})
+
+	t.Run("with an undefined variable", func(t *testing.T) {
+		v, err := httpstat.RunTransaction([]httpstat.Step{
+			{URL: s.URL + "/users/{{id}}"},
+		})
+
+		assert.EqualError(t, err, `step 0: variable "id" is not defined`)
+		assert.Empty(t, v.Steps, "steps")
+	})
+
+	t.Run("with large numbers", func(t *testing.T) {
+		v, err := httpstat.RunTransaction([]httpstat.Step{
+			{URL: s.URL + "/orders", Extract: []httpstat.Extract{{Var: "id", JSON: "id"}, {Var: "total", JSON: "total"}}},
+		})
+
+		assert.NoError(t, err, "transaction")
+		assert.Equal(t, "12345678901234567890", v.Vars["id"])
+		assert.Equal(t, "1.50", v.Vars["total"])
+	})
+
+	t.Run("with a missing value", func(t *testing.T) {
+		_, err := httpstat.RunTransaction([]httpstat.Step{
+			{URL: s.URL + "/login", Extract: []httpstat.Extract{{Var: "id", JSON: "user.id"}}},
+		})
+
+		assert.Contains(t, err.Error(), `step 0: extracting "id": parsing JSON`)
+	})
+}
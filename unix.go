@@ -0,0 +1,69 @@
+package httpstat
+
+import (
+	"context"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixHost is the host of requests made over Unix sockets.
+const unixHost = "localhost"
+
+// unixKey is the context key of the Unix socket path of a request.
+type unixKey struct{}
+
+// unixURL parses a Unix socket URL of the form "unix:///run/app.sock:/path",
+// returning the socket path and the HTTP URL of the request. The path starts
+// at the first ":/" ending the path of an existing socket, or the first ":/"
+// without one, so that the socket path may contain colons, and the path and
+// query of the request may contain ":/".
+func unixURL(uri string) (socket, httpURL string, ok bool) {
+	rest, ok := strings.CutPrefix(uri, "unix://")
+	if !ok {
+		return "", "", false
+	}
+
+	socket, path := rest, "/"
+	if i := unixPathIndex(rest); i >= 0 {
+		socket, path = rest[:i], rest[i+1:]
+	}
+
+	return socket, "http://" + unixHost + path, true
+}
+
+// unixPathIndex returns the index of the ":/" separating the socket path
+// and the request path of s, or -1 without one.
+func unixPathIndex(s string) int {
+	first := strings.Index(s, ":/")
+
+	for i := first; i >= 0; {
+		if info, err := os.Stat(s[:i]); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			return i
+		}
+
+		n := strings.Index(s[i+2:], ":/")
+		if n < 0 {
+			break
+		}
+		i += n + 2
+	}
+
+	return first
+}
+
+// unixSocket returns the Unix socket path of ctx when addr,
+// with or without its port, is the host of Unix socket requests.
+func unixSocket(ctx context.Context, addr string) (string, bool) {
+	if host, port, err := net.SplitHostPort(addr); err == nil && port == "80" {
+		addr = host
+	}
+
+	if addr != unixHost {
+		return "", false
+	}
+
+	path, ok := ctx.Value(unixKey{}).(string)
+	return path, ok
+}